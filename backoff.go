@@ -0,0 +1,131 @@
+package klient
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+var ErrBackoffType = errors.New("unsupported backoff type")
+
+// BackoffType is the name of the backoff strategy.
+type BackoffType string
+
+const (
+	// BackoffExponential waits min * 2^attempt, default of the client.
+	BackoffExponential BackoffType = "exponential"
+	// BackoffLinearJitter waits a random duration between min and max multiplied by the attempt number.
+	BackoffLinearJitter BackoffType = "linear_jitter"
+	// BackoffConstant waits always min.
+	BackoffConstant BackoffType = "constant"
+	// BackoffDecorrelatedJitter waits a random duration between min and 3 times the previous wait.
+	BackoffDecorrelatedJitter BackoffType = "decorrelated_jitter"
+)
+
+// NewBackoff returns the backoff of the type.
+//
+// Jitter is the random factor in [0, 1] applied to exponential and constant waits,
+// e.g. 0.2 changes the wait randomly by up to ±20%.
+// Linear and decorrelated jitter backoffs are random by themselves and ignore it.
+//
+// All types wait the Retry-After header of 429 and 503 responses.
+func NewBackoff(backoffType BackoffType, jitter float64) (retryablehttp.Backoff, error) {
+	return newBackoff(backoffType, jitter, systemClock{})
+}
+
+// newBackoff is NewBackoff with the clock of the Retry-After date.
+func newBackoff(backoffType BackoffType, jitter float64, clock Clock) (retryablehttp.Backoff, error) {
+	if jitter < 0 || jitter > 1 {
+		return nil, fmt.Errorf("%w: jitter %v not in [0, 1]", ErrBackoffType, jitter)
+	}
+
+	var backoff retryablehttp.Backoff
+
+	switch backoffType {
+	case BackoffExponential, "":
+		backoff = withJitter(func(minWait, maxWait time.Duration, attemptNum int, _ *http.Response) time.Duration {
+			wait := float64(minWait) * math.Pow(2, float64(attemptNum))
+			if wait > float64(maxWait) {
+				return maxWait
+			}
+
+			return time.Duration(wait)
+		}, jitter)
+	case BackoffLinearJitter:
+		return retryablehttp.RateLimitLinearJitterBackoff, nil
+	case BackoffConstant:
+		backoff = withJitter(func(minWait, _ time.Duration, _ int, _ *http.Response) time.Duration {
+			return minWait
+		}, jitter)
+	case BackoffDecorrelatedJitter:
+		backoff = decorrelatedJitter
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrBackoffType, backoffType)
+	}
+
+	return func(minWait, maxWait time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		if wait, ok := retryAfter(clock, resp); ok {
+			return wait
+		}
+
+		return backoff(minWait, maxWait, attemptNum, resp)
+	}, nil
+}
+
+func withJitter(backoff retryablehttp.Backoff, jitter float64) retryablehttp.Backoff {
+	if jitter == 0 {
+		return backoff
+	}
+
+	return func(minWait, maxWait time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		wait := float64(backoff(minWait, maxWait, attemptNum, resp))
+		wait += wait * jitter * (2*rand.Float64() - 1) //nolint:gosec // not for security
+
+		return min(time.Duration(wait), maxWait)
+	}
+}
+
+// decorrelatedJitter replays the random walk of the previous attempts, backoff has no state between attempts.
+func decorrelatedJitter(minWait, maxWait time.Duration, attemptNum int, _ *http.Response) time.Duration {
+	wait := minWait
+	for range attemptNum + 1 {
+		upper := wait * 3
+		if upper <= minWait {
+			wait = minWait
+
+			continue
+		}
+
+		wait = min(minWait+rand.N(upper-minWait), maxWait) //nolint:gosec // not for security
+	}
+
+	return wait
+}
+
+// retryAfter returns the wait of the Retry-After header of 429 and 503 responses.
+func retryAfter(clock Clock, resp *http.Response) (time.Duration, bool) {
+	if resp == nil || (resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable) {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return max(0, time.Duration(seconds)*time.Second), true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(0, t.Sub(clock.Now())), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,133 @@
+package klient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewBackoff(t *testing.T) {
+	minWait, maxWait := 100*time.Millisecond, time.Second
+
+	tests := []struct {
+		backoff BackoffType
+		jitter  float64
+		check   func(attempt int, wait time.Duration) bool
+	}{
+		{
+			backoff: BackoffExponential,
+			check: func(attempt int, wait time.Duration) bool {
+				return wait == min(minWait<<attempt, maxWait)
+			},
+		},
+		{
+			backoff: BackoffExponential,
+			jitter:  0.5,
+			check: func(attempt int, wait time.Duration) bool {
+				base := min(minWait<<attempt, maxWait)
+
+				return wait >= base/2 && wait <= min(base*3/2, maxWait)
+			},
+		},
+		{
+			backoff: BackoffConstant,
+			check: func(_ int, wait time.Duration) bool {
+				return wait == minWait
+			},
+		},
+		{
+			backoff: BackoffLinearJitter,
+			check: func(attempt int, wait time.Duration) bool {
+				return wait >= minWait*time.Duration(attempt+1) && wait <= maxWait*time.Duration(attempt+1)
+			},
+		},
+		{
+			backoff: BackoffDecorrelatedJitter,
+			check: func(_ int, wait time.Duration) bool {
+				return wait >= minWait && wait <= maxWait
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.backoff), func(t *testing.T) {
+			backoff, err := NewBackoff(tt.backoff, tt.jitter)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for attempt := range 6 {
+				for range 20 {
+					if wait := backoff(minWait, maxWait, attempt, nil); !tt.check(attempt, wait) {
+						t.Fatalf("unexpected wait %s of attempt %d", wait, attempt)
+					}
+				}
+			}
+
+			resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"3"}}}
+			if wait := backoff(minWait, maxWait, 0, resp); wait != 3*time.Second {
+				t.Errorf("expected Retry-After wait, got %s", wait)
+			}
+		})
+	}
+
+	if _, err := NewBackoff("fibonacci", 0); !errors.Is(err, ErrBackoffType) {
+		t.Errorf("expected backoff type error, got %v", err)
+	}
+}
+
+func TestConfigRetry(t *testing.T) {
+	var calls atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer server.Close()
+
+	retryLog := false
+	cfg := Config{
+		BaseURL:      server.URL,
+		RetryMax:     2,
+		RetryWaitMin: time.Millisecond,
+		RetryWaitMax: time.Millisecond,
+		Retry: &RetryConfig{
+			EnabledStatusCodes: []int{http.StatusConflict},
+			Methods:            []string{http.MethodGet},
+			Backoff:            BackoffConstant,
+			Log:                &retryLog,
+		},
+	}
+
+	client, err := cfg.New(WithDisableEnvValues(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		method string
+		calls  int32
+	}{
+		{method: http.MethodGet, calls: 3},
+		{method: http.MethodPost, calls: 1},
+	} {
+		calls.Store(0)
+
+		req, _ := http.NewRequestWithContext(t.Context(), tt.method, "/", nil)
+		_ = client.Do(req, func(*http.Response) error { return nil })
+
+		if got := calls.Load(); got != tt.calls {
+			t.Errorf("%s: expected %d calls, got %d", tt.method, tt.calls, got)
+		}
+	}
+
+	cfg.Retry = &RetryConfig{Backoff: "fibonacci", Jitter: 2, DisabledStatusCodes: []int{1000}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected validation error")
+	} else if want := "retry.backoff: unsupported backoff \"fibonacci\"\nretry.jitter: must be in [0, 1]\nretry.disabled_status_codes: invalid status code 1000"; err.Error() != want {
+		t.Errorf("unexpected error\n%v\nwant\n%s", err, want)
+	}
+}
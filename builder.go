@@ -0,0 +1,250 @@
+package klient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var ErrPathParams = errors.New("path parameters mismatch")
+
+// RequestBuilder builds a *http.Request with fluent methods.
+//
+// Errors are collected and returned with Build.
+//
+//	req, err := client.NewRequest(ctx).
+//		Method(http.MethodPost).
+//		Path("/users/{id}/items", id).
+//		Query(ListQuery{Limit: 10}).
+//		Header("X-Info", "example").
+//		JSON(body).
+//		Build()
+type RequestBuilder struct {
+	client *Client
+	ctx    context.Context
+
+	method   string
+	path     string
+	rawQuery string
+	query    url.Values
+	header   http.Header
+	body     io.Reader
+
+	err error
+}
+
+// NewRequestBuilder returns a new request builder, default method is GET.
+//
+// Useful to implement Requester.Request.
+func NewRequestBuilder(ctx context.Context) *RequestBuilder {
+	return &RequestBuilder{
+		ctx:    ctx,
+		method: http.MethodGet,
+		query:  url.Values{},
+		header: http.Header{},
+	}
+}
+
+// NewRequest returns a new request builder bounded to the client, see NewRequestBuilder.
+func (c *Client) NewRequest(ctx context.Context) *RequestBuilder {
+	b := NewRequestBuilder(ctx)
+	b.client = c
+
+	return b
+}
+
+// Method sets the HTTP method.
+func (b *RequestBuilder) Method(method string) *RequestBuilder {
+	b.method = method
+
+	return b
+}
+
+// Path sets the path with replacing {name} placeholders in order with escaped args.
+//
+// Relative paths are resolved with the base URL of the client.
+// Query after "?" in the template is kept and merged with Query values, placeholders there are query escaped.
+//
+//	Path("/users/{id}/files/{name}", 42, "a/b.txt") // /users/42/files/a%2Fb.txt
+//	Path("/users?name={name}", "a&b")               // /users?name=a%26b
+func (b *RequestBuilder) Path(template string, args ...any) *RequestBuilder {
+	var (
+		sb    strings.Builder
+		index int
+		query bool
+	)
+
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			sb.WriteString(rest)
+
+			break
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			b.err = errors.Join(b.err, fmt.Errorf("%w: unclosed placeholder in %q", ErrPathParams, template))
+
+			return b
+		}
+
+		if index >= len(args) {
+			b.err = errors.Join(b.err, fmt.Errorf("%w: missing value of %s in %q", ErrPathParams, rest[start:start+end+1], template))
+
+			return b
+		}
+
+		sb.WriteString(rest[:start])
+
+		query = query || strings.Contains(rest[:start], "?")
+		if query {
+			sb.WriteString(url.QueryEscape(fmt.Sprint(args[index])))
+		} else {
+			sb.WriteString(url.PathEscape(fmt.Sprint(args[index])))
+		}
+
+		index++
+		rest = rest[start+end+1:]
+	}
+
+	if index != len(args) {
+		b.err = errors.Join(b.err, fmt.Errorf("%w: %d values for %d placeholders in %q", ErrPathParams, len(args), index, template))
+
+		return b
+	}
+
+	b.path, b.rawQuery, _ = strings.Cut(sb.String(), "?")
+
+	return b
+}
+
+// Query adds query parameters, see EncodeQuery for supported values.
+func (b *RequestBuilder) Query(v any) *RequestBuilder {
+	values, err := EncodeQuery(v)
+	if err != nil {
+		b.err = errors.Join(b.err, err)
+
+		return b
+	}
+
+	for k, vs := range values {
+		b.query[k] = append(b.query[k], vs...)
+	}
+
+	return b
+}
+
+// QueryParam adds values to the query parameter.
+func (b *RequestBuilder) QueryParam(key string, values ...string) *RequestBuilder {
+	b.query[key] = append(b.query[key], values...)
+
+	return b
+}
+
+// Header adds the header value.
+func (b *RequestBuilder) Header(key, value string) *RequestBuilder {
+	b.header.Add(key, value)
+
+	return b
+}
+
+// Headers adds all values of header.
+func (b *RequestBuilder) Headers(header http.Header) *RequestBuilder {
+	for k, vs := range header {
+		for _, v := range vs {
+			b.header.Add(k, v)
+		}
+	}
+
+	return b
+}
+
+// Body sets the request body.
+//
+// Use bytes.Reader, bytes.Buffer or strings.Reader to make it replayable.
+func (b *RequestBuilder) Body(body io.Reader) *RequestBuilder {
+	b.body = body
+
+	return b
+}
+
+// JSON sets the json encoded body and Content-Type header.
+func (b *RequestBuilder) JSON(v any) *RequestBuilder {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.err = errors.Join(b.err, fmt.Errorf("marshal request body: %w", err))
+
+		return b
+	}
+
+	b.body = bytes.NewReader(data)
+	b.header.Set("Content-Type", "application/json")
+
+	return b
+}
+
+// Build returns the http request.
+func (b *RequestBuilder) Build() (*http.Request, error) {
+	if b.err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCreateRequest, b.err)
+	}
+
+	u := &url.URL{Path: b.path, RawQuery: b.rawQuery}
+	if raw, err := url.PathUnescape(b.path); err == nil {
+		u.Path = raw
+		u.RawPath = b.path
+	}
+
+	// absolute path given
+	if strings.Contains(b.path, "://") {
+		parsed, err := url.Parse(b.path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCreateRequest, err)
+		}
+
+		parsed.RawQuery = b.rawQuery
+		u = parsed
+	}
+
+	if len(b.query) > 0 {
+		query := u.Query()
+		for k, vs := range b.query {
+			query[k] = append(query[k], vs...)
+		}
+
+		u.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(b.ctx, b.method, u.String(), b.body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCreateRequest, err)
+	}
+
+	for k, vs := range b.header {
+		req.Header[k] = append(req.Header[k], vs...)
+	}
+
+	return req, nil
+}
+
+// Do builds the request and sends it with the client, see Client.Do.
+func (b *RequestBuilder) Do(fn func(*http.Response) error) error {
+	if b.client == nil {
+		return fmt.Errorf("%w: request builder without client", ErrRequest)
+	}
+
+	req, err := b.Build()
+	if err != nil {
+		return err
+	}
+
+	return b.client.Do(req, fn)
+}
@@ -0,0 +1,128 @@
+package klient
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testQuery struct {
+	Name   string    `query:"name,omitempty"`
+	Empty  string    `query:"empty,omitempty"`
+	IDs    []int     `query:"id"`
+	Since  time.Time `query:"since"`
+	Before time.Time `query:"before,unix"`
+	Active *bool     `query:"active,omitempty"`
+	Skip   string    `query:"-"`
+	Limit  uint
+	testEmbedded
+}
+
+type testEmbedded struct {
+	Sort string `query:"sort"`
+}
+
+func TestEncodeQuery(t *testing.T) {
+	active := false
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	values, err := EncodeQuery(testQuery{
+		Name:         "a b",
+		IDs:          []int{1, 2},
+		Since:        since,
+		Before:       since,
+		Active:       &active,
+		Skip:         "skip",
+		Limit:        10,
+		testEmbedded: testEmbedded{Sort: "desc"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Limit=10&active=false&before=1704164645&id=1&id=2&name=a+b&since=2024-01-02T03%3A04%3A05Z&sort=desc"
+	if got := values.Encode(); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+
+	if _, err := EncodeQuery(42); !errors.Is(err, ErrQueryType) {
+		t.Fatalf("expected ErrQueryType, got %v", err)
+	}
+}
+
+func TestRequestBuilder(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"method": r.Method,
+			"path":   r.URL.EscapedPath(),
+			"query":  r.URL.RawQuery,
+			"header": r.Header.Get("X-Info"),
+			"type":   r.Header.Get("Content-Type"),
+			"body":   string(body),
+		})
+	}))
+	defer httpServer.Close()
+
+	client, err := New(WithBaseURL(httpServer.URL + "/api/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]string
+	if err := client.NewRequest(t.Context()).
+		Method(http.MethodPost).
+		Path("users/{id}/files/{name}", 42, "a/b c.txt").
+		Query(map[string]string{"limit": "10"}).
+		QueryParam("tag", "x", "y").
+		Header("X-Info", "test").
+		JSON(map[string]int{"id": 1}).
+		Do(ResponseFuncJSON(&got)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"method": http.MethodPost,
+		"path":   "/api/users/42/files/a%2Fb%20c.txt",
+		"query":  "limit=10&tag=x&tag=y",
+		"header": "test",
+		"type":   "application/json",
+		"body":   `{"id":1}`,
+	}
+
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: expected %q, got %q", k, v, got[k])
+		}
+	}
+
+	req, err := NewRequestBuilder(t.Context()).
+		Path("/users/{id}?name={name}&active", "a?b", "c&d").
+		QueryParam("limit", "10").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.URL.EscapedPath() != "/users/a%3Fb" || req.URL.Query().Get("name") != "c&d" || !req.URL.Query().Has("active") || req.URL.Query().Get("limit") != "10" {
+		t.Errorf("unexpected url %s", req.URL)
+	}
+
+	req, err = NewRequestBuilder(t.Context()).Path("http://example.com/users?page=2").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.URL.String() != "http://example.com/users?page=2" {
+		t.Errorf("unexpected url %s", req.URL)
+	}
+
+	if _, err := NewRequestBuilder(t.Context()).Path("/users/{id}").Build(); !errors.Is(err, ErrPathParams) {
+		t.Fatalf("expected ErrPathParams, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package klient
 
 import (
+	"cmp"
 	"context"
 	"crypto/tls"
 	"errors"
@@ -9,7 +10,9 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"strconv"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-cleanhttp"
@@ -20,6 +23,8 @@ import (
 
 var (
 	defaultMaxConnections = 100
+	defaultDialTimeout    = 30 * time.Second
+	defaultKeepAlive      = 30 * time.Second
 
 	defaultRetryWaitMin = 1 * time.Second
 	defaultRetryWaitMax = 30 * time.Second
@@ -31,8 +36,13 @@ var (
 	// DisableEnvValues when true will disable all env values check.
 	DisableEnvValues = false
 
+	// EnvKlientPrefix is the prefix of env values, see ConfigFromEnv.
+	EnvKlientPrefix        = "KLIENT_"
+	EnvKlientBaseURLGlobal = "API_GATEWAY_ADDRESS"
+
+	// Env values before ConfigFromEnv, now read with the Config fields.
+	// Malformed values of them are ignored as before, other env values return ErrEnvValue.
 	EnvKlientBaseURL            = "KLIENT_BASE_URL"
-	EnvKlientBaseURLGlobal      = "API_GATEWAY_ADDRESS"
 	EnvKlientInsecureSkipVerify = "KLIENT_INSECURE_SKIP_VERIFY"
 	EnvKlientTimeout            = "KLIENT_TIMEOUT"
 	EnvKlientRetryDisable       = "KLIENT_RETRY_DISABLE"
@@ -40,6 +50,54 @@ var (
 
 type Client struct {
 	HTTP *http.Client
+
+	// transport is the swappable transport chain of HTTP, see Reload.
+	transport reloadTransport
+	// base is the http client before configuring, copied for Reload.
+	base http.Client
+	// baseOwned is true when the base transport is created by klient.
+	baseOwned bool
+	// options and config are the options of New applied again on Reload, config is replaced.
+	options []OptionClientFn
+	config  OptionClientFn
+	timeout time.Duration
+	mutex   sync.Mutex
+}
+
+// streamValue holds the transport chain of the client with the backoff settings for long living requests.
+//
+// Requests with ctxWithStream skip the retry and the retry timeout, RetryMax is 0 when retry is disabled.
+type streamValue struct {
+	Transport    http.RoundTripper
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	RetryMax     int
+	Backoff      retryablehttp.Backoff
+	Clock        Clock
+	Logger       logz.Adapter
+}
+
+// ctxKeyStream marks the long living requests which handle their own reconnects.
+const ctxKeyStream ctxKey = "stream"
+
+func ctxWithStream(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyStream, true)
+}
+
+// streamTransport sends the stream requests to the transport without retry.
+type streamTransport struct {
+	base   http.RoundTripper
+	stream http.RoundTripper
+}
+
+var _ http.RoundTripper = (*streamTransport)(nil)
+
+func (t *streamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if stream, _ := req.Context().Value(ctxKeyStream).(bool); stream {
+		return t.stream.RoundTrip(req)
+	}
+
+	return t.base.RoundTrip(req)
 }
 
 // NewPlain creates a new http client with the some default disabled automatic features.
@@ -59,21 +117,138 @@ func NewPlain(opts ...OptionClientFn) (*Client, error) {
 // New creates a new http client with the provided options.
 //
 // Default BaseURL is required, it can be disabled by setting DisableBaseURLCheck to true.
+//
+// Values are applied with the precedence, highest first:
+//   - options (Config.New applies the Config after its options)
+//   - named env values KLIENT_<NAME>_*, see WithName
+//   - global env values KLIENT_*
+//   - defaults
+//
+// For backward compatibility KLIENT_TIMEOUT, KLIENT_INSECURE_SKIP_VERIFY=true and
+// KLIENT_RETRY_DISABLE=true (also the named ones) override the options.
+// Malformed env values return ErrEnvValue, except these legacy global values which are ignored,
+// use WithDisableEnvValues to not read env values.
+// Base URL is selected from options, DefaultBaseURL, KLIENT_<NAME>_BASE_URL, KLIENT_BASE_URL and API_GATEWAY_ADDRESS in order.
 func New(opts ...OptionClientFn) (*Client, error) {
-	logAdapter := logz.AdapterKV{Log: log.Logger, Caller: true}
-	o := optionClientValue{
-		PooledClient:   true,
-		MaxConnections: defaultMaxConnections,
-		RetryWaitMin:   defaultRetryWaitMin,
-		RetryWaitMax:   defaultRetryWaitMax,
-		RetryMax:       defaultRetryMax,
-		Backoff:        retryablehttp.DefaultBackoff,
-		Logger:         logAdapter,
-		RetryLog:       true,
+	return newClient(opts, nil)
+}
+
+// newClient creates the client, config is applied after the options and replaced by Reload.
+func newClient(opts []OptionClientFn, config OptionClientFn) (*Client, error) {
+	o, baseURL, err := newClientOption(clientOptions(opts, config))
+	if err != nil {
+		return nil, err
 	}
 
-	for _, opt := range opts {
-		opt(&o)
+	// create client
+	client := o.HTTPClient
+	if client == nil {
+		switch {
+		case o.PooledClient:
+			client = cleanhttp.DefaultPooledClient()
+		default:
+			client = cleanhttp.DefaultClient()
+		}
+
+		if transport, ok := client.Transport.(*http.Transport); ok && o.BaseTransport == nil {
+			o.ownedTransport = transport
+		}
+	}
+
+	if o.BaseTransport != nil {
+		client.Transport = o.BaseTransport
+	}
+
+	// keep the base transport unconfigured for Reload
+	base := *client
+	baseOwned := false
+	if transport, ok := client.Transport.(*http.Transport); ok {
+		if o.isSharedTransport(transport) {
+			base.Transport = newBaseTransport(o.PooledClient)
+			baseOwned = true
+		} else {
+			base.Transport = transport.Clone()
+			baseOwned = transport == o.ownedTransport
+		}
+	}
+
+	state, err := o.newClientState(client, baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		HTTP:      client,
+		base:      base,
+		baseOwned: baseOwned,
+		options:   opts,
+		config:    config,
+		timeout:   o.Timeout,
+	}
+
+	if !o.DisableRetry {
+		// retry client uses the given client for redirects and cookies
+		c.HTTP = &http.Client{}
+	}
+
+	c.transport.state.Store(state)
+	c.HTTP.Transport = &c.transport
+
+	if o.Timeout > 0 {
+		c.HTTP.Timeout = o.Timeout
+	}
+
+	return c, nil
+}
+
+func clientOptions(opts []OptionClientFn, config OptionClientFn) []OptionClientFn {
+	if config == nil {
+		return opts
+	}
+
+	return append(slices.Clone(opts), config)
+}
+
+// newClientOption returns the option values with env values and defaults, validated.
+func newClientOption(opts []OptionClientFn) (optionClientValue, *url.URL, error) {
+	o := newOptionClientValue(opts)
+
+	if DisableEnvValues {
+		o.DisableEnvValues = true
+	}
+
+	var envConfig Config
+	if !o.DisableEnvValues {
+		var err error
+		envConfig, err = ConfigFromEnv(o.Name)
+		if err != nil {
+			return o, nil, fmt.Errorf("failed to load env values: %w", err)
+		}
+
+		envBaseURL := envConfig.BaseURL
+		envConfig.BaseURL = ""
+
+		// apply again to place env values under the options
+		opts = append([]OptionClientFn{envConfig.ToOption()}, opts...)
+		o = newOptionClientValue(opts)
+
+		envConfig.BaseURL = envBaseURL
+
+		if envConfig.Timeout > 0 {
+			o.Timeout = envConfig.Timeout
+		}
+
+		if envConfig.InsecureSkipVerify != nil && *envConfig.InsecureSkipVerify {
+			o.InsecureSkipVerify = true
+		}
+
+		if envConfig.DisableRetry != nil && *envConfig.DisableRetry {
+			o.DisableRetry = true
+		}
+	}
+
+	if o.Clock == nil {
+		o.Clock = systemClock{}
 	}
 
 	if o.RetryPolicy == nil {
@@ -89,17 +264,13 @@ func New(opts ...OptionClientFn) (*Client, error) {
 		}
 	}
 
-	if DisableEnvValues {
-		o.DisableEnvValues = true
-	}
-
 	var baseURL *url.URL
 	if o.BaseURL == "" {
 		baseURL := DefaultBaseURL
 
 		if !o.DisableEnvValues {
 			if baseURL == "" {
-				baseURL = os.Getenv(EnvKlientBaseURL)
+				baseURL = envConfig.BaseURL
 			}
 			if baseURL == "" {
 				baseURL = os.Getenv(EnvKlientBaseURLGlobal)
@@ -109,105 +280,82 @@ func New(opts ...OptionClientFn) (*Client, error) {
 		o.BaseURL = baseURL
 	}
 
-	if !o.DisableBaseURLCheck {
-		if o.BaseURL == "" {
-			return nil, fmt.Errorf("base url is required")
-		}
+	if err := o.validate(); err != nil {
+		return o, nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
 	}
 
-	if o.BaseURL != "" {
-		var err error
-		baseURL, err = url.Parse(o.BaseURL)
+	if o.RetryBackoff != "" || o.RetryJitter != 0 {
+		backoff, err := newBackoff(o.RetryBackoff, o.RetryJitter, o.Clock)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse base url: %w", err)
+			return o, nil, err
 		}
-	}
 
-	// create client
-	client := o.HTTPClient
-	if client == nil {
-		switch {
-		case o.PooledClient:
-			client = cleanhttp.DefaultPooledClient()
-		default:
-			client = cleanhttp.DefaultClient()
-		}
+		o.Backoff = backoff
 	}
 
-	if o.BaseTransport != nil {
-		client.Transport = o.BaseTransport
-	}
-
-	if o.HTTP2 {
-		var protocols http.Protocols
-		protocols.SetUnencryptedHTTP2(true)
-
-		transport, ok := client.Transport.(*http.Transport)
-		if !ok {
-			return nil, fmt.Errorf("failed to cast transport to http.Transport")
+	if !o.DisableBaseURLCheck {
+		if o.BaseURL == "" {
+			return o, nil, fmt.Errorf("base url is required")
 		}
-
-		transport.ForceAttemptHTTP2 = true
-		transport.Protocols = &protocols
 	}
 
-	// make always after client creation
-	if o.Proxy != "" {
-		u, err := url.Parse(o.Proxy)
+	if o.BaseURL != "" {
+		var err error
+		baseURL, err = url.Parse(o.BaseURL)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse proxy url: %w", err)
+			return o, nil, fmt.Errorf("failed to parse base url: %w", err)
 		}
 
-		transport, ok := client.Transport.(*http.Transport)
-		if !ok {
-			return nil, fmt.Errorf("failed to cast transport to http.Transport")
+		if !o.DisableBaseURLCheck && (baseURL.Scheme == "" || baseURL.Host == "") && o.Logger != nil {
+			o.Logger.Warn("base url has no scheme or host", "base_url", baseURL.Redacted())
 		}
-
-		transport.Proxy = http.ProxyURL(u)
 	}
 
-	// skip verify
-	if !o.DisableEnvValues {
-		if v, _ := strconv.ParseBool(os.Getenv(EnvKlientInsecureSkipVerify)); v {
-			o.InsecureSkipVerify = true
-		}
-	}
+	return o, baseURL, nil
+}
 
-	if o.TLSConfig != nil {
-		tlsClientConfig, err := o.TLSConfig.Generate()
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate tls config: %w", err)
-		}
+// newClientState builds the transport chain over the transport of the client.
+//
+// Client is used by the retry client, its transport is changed.
+func (o *optionClientValue) newClientState(client *http.Client, baseURL *url.URL) (*clientState, error) {
+	state := &clientState{}
+	if transport, ok := client.Transport.(*http.Transport); ok && !o.isSharedTransport(transport) {
+		state.base = transport
+	}
 
-		if transport, ok := client.Transport.(*http.Transport); ok {
-			transport.TLSClientConfig = tlsClientConfig
+	if !o.isSharedTransport(client.Transport) {
+		if err := o.configureTransport(client); err != nil {
+			return nil, err
 		}
 	}
 
-	if o.InsecureSkipVerify {
-		if transport, ok := client.Transport.(*http.Transport); ok {
-			tlsClientConfig := transport.TLSClientConfig
-			if tlsClientConfig == nil {
-				tlsClientConfig = &tls.Config{
-					//nolint:gosec // user defined
-					InsecureSkipVerify: true,
-				}
-			} else {
-				tlsClientConfig.InsecureSkipVerify = true
+	if !o.DisableDecompression && len(o.AcceptEncoding) > 0 {
+		for _, encoding := range o.AcceptEncoding {
+			if !isSupportedEncoding(encoding) {
+				return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
 			}
+		}
 
-			transport.TLSClientConfig = tlsClientConfig
+		client.Transport = &decompressTransport{
+			base:           transportOrDefault(client.Transport),
+			acceptEncoding: strings.Join(o.AcceptEncoding, ", "),
 		}
 	}
 
-	// disable
-	if !o.DisableEnvValues {
-		if v, _ := strconv.ParseBool(os.Getenv(EnvKlientRetryDisable)); v {
-			o.DisableRetry = true
-		}
+	stream := streamValue{
+		RetryWaitMin: o.RetryWaitMin,
+		RetryWaitMax: o.RetryWaitMax,
+		RetryMax:     o.RetryMax,
+		Backoff:      o.Backoff,
+		Clock:        o.Clock,
+		Logger:       o.Logger,
 	}
 
-	if !o.DisableRetry {
+	if o.DisableRetry {
+		stream.RetryMax = 0
+	} else {
+		direct := client.Transport
+
 		// Wrap the transport with retry timeout BEFORE creating the retry client
 		// This ensures each attempt gets its own timeout
 		// Note: Skip retryTimeoutTransport for HTTP2 as it doesn't work well with
@@ -217,22 +365,74 @@ func New(opts ...OptionClientFn) (*Client, error) {
 			client.Transport = &retryTimeoutTransport{
 				base:    baseTransport,
 				timeout: o.RetryTimeout,
+				clock:   o.Clock,
 			}
 		}
 
 		// create retry client
-		retryClient := retryablehttp.Client{
-			HTTPClient:   client,
-			Logger:       o.Logger,
-			RetryWaitMin: o.RetryWaitMin,
-			RetryWaitMax: o.RetryWaitMax,
-			RetryMax:     o.RetryMax,
-			CheckRetry:   o.RetryPolicy,
-			Backoff:      o.Backoff,
-			ErrorHandler: PassthroughErrorHandler,
+		attemptClient := client
+		newRetryClient := func(backoff retryablehttp.Backoff) *retryablehttp.Client {
+			return &retryablehttp.Client{
+				HTTPClient:   attemptClient,
+				Logger:       o.Logger,
+				RetryWaitMin: o.RetryWaitMin,
+				RetryWaitMax: o.RetryWaitMax,
+				RetryMax:     o.RetryMax,
+				CheckRetry:   retryDisabledPolicy(o.RetryPolicy),
+				Backoff:      backoff,
+				ErrorHandler: PassthroughErrorHandler,
+			}
+		}
+
+		if isSystemClock(o.Clock) {
+			client = newRetryClient(o.Backoff).StandardClient()
+		} else {
+			client = &http.Client{Transport: &clockRetryTransport{
+				newClient: newRetryClient,
+				backoff:   o.Backoff,
+				clock:     o.Clock,
+			}}
+		}
+
+		if o.BodyReplayMemoryLimit <= 0 {
+			o.BodyReplayMemoryLimit = DefaultBodyReplayMemoryLimit
 		}
 
-		client = retryClient.StandardClient()
+		client.Transport = &replayTransport{
+			base:        client.Transport,
+			mode:        o.BodyReplay,
+			memoryLimit: o.BodyReplayMemoryLimit,
+			log:         o.Logger,
+		}
+
+		if len(o.RetryMethods) > 0 {
+			client.Transport = &retryMethodTransport{
+				base:    client.Transport,
+				methods: o.RetryMethods,
+			}
+		}
+
+		client.Transport = &streamTransport{
+			base:   client.Transport,
+			stream: direct,
+		}
+	}
+
+	if o.CompressionEncoding != "" {
+		if o.CompressionEncoding != EncodingGzip && o.CompressionEncoding != EncodingZstd {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, o.CompressionEncoding)
+		}
+
+		client.Transport = &requestCompressTransport{
+			base:     transportOrDefault(client.Transport),
+			encoding: o.CompressionEncoding,
+			minSize:  o.CompressionMinSize,
+		}
+	}
+
+	client.Transport = &maxBodyTransport{
+		base: transportOrDefault(client.Transport),
+		max:  o.MaxResponseBodySize,
 	}
 
 	client.Transport = &TransportKlient{
@@ -262,20 +462,70 @@ func New(opts ...OptionClientFn) (*Client, error) {
 		}
 	}
 
-	// set timeout
-	if !o.DisableEnvValues {
-		if v, _ := time.ParseDuration(os.Getenv(EnvKlientTimeout)); v > 0 {
-			o.Timeout = v
+	stream.Transport = client.Transport
+
+	state.transport = client.Transport
+	state.stream = stream
+
+	return state, nil
+}
+
+// applyTransport sets the pool and timeout options to the transport, zero values keep the transport values.
+func (o *optionClientValue) applyTransport(transport *http.Transport) {
+	if o.MaxConnections > 0 {
+		transport.MaxIdleConns = o.MaxConnections
+		transport.MaxIdleConnsPerHost = o.MaxConnections
+	}
+
+	if o.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = o.MaxConnsPerHost
+	}
+
+	if o.DialTimeout != 0 || o.KeepAlive != 0 {
+		dialer := &net.Dialer{
+			Timeout:   cmp.Or(o.DialTimeout, defaultDialTimeout),
+			KeepAlive: cmp.Or(o.KeepAlive, defaultKeepAlive),
 		}
+
+		transport.DialContext = dialer.DialContext
 	}
 
-	if o.Timeout > 0 {
-		client.Timeout = o.Timeout
+	if o.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = o.IdleConnTimeout
+	}
+
+	if o.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = o.TLSHandshakeTimeout
+	}
+
+	if o.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = o.ResponseHeaderTimeout
+	}
+
+	if o.ExpectContinueTimeout > 0 {
+		transport.ExpectContinueTimeout = o.ExpectContinueTimeout
+	}
+}
+
+// newOptionClientValue returns the default options with applied opts.
+func newOptionClientValue(opts []OptionClientFn) optionClientValue {
+	o := optionClientValue{
+		PooledClient:   true,
+		MaxConnections: defaultMaxConnections,
+		RetryWaitMin:   defaultRetryWaitMin,
+		RetryWaitMax:   defaultRetryWaitMax,
+		RetryMax:       defaultRetryMax,
+		Backoff:        retryablehttp.DefaultBackoff,
+		Logger:         logz.AdapterKV{Log: log.Logger, Caller: true},
+		RetryLog:       true,
+		AcceptEncoding: DefaultAcceptEncoding,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	return &Client{
-		HTTP: client,
-	}, nil
+	return o
 }
 
 // isTimeoutError checks if an error is a timeout or deadline exceeded error.
@@ -297,3 +547,106 @@ func isTimeoutError(err error) bool {
 
 	return false
 }
+
+func transportOrDefault(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		return http.DefaultTransport
+	}
+
+	return transport
+}
+
+// tlsReloadError logs the failed TLS file reload and calls the hook.
+func (o *optionClientValue) tlsReloadError(err error) {
+	if o.Logger != nil {
+		o.Logger.Warn("tls reload failed, using the last good files", "error", err)
+	}
+
+	if o.TLSReloadHook != nil {
+		o.TLSReloadHook(err)
+	}
+}
+
+// tlsPinReport logs the pin mismatches of the report only mode.
+func (o *optionClientValue) tlsPinReport(err error) {
+	if o.Logger != nil {
+		o.Logger.Warn("certificate pin mismatch", "error", err)
+	}
+}
+
+// isSharedTransport reports the base transport is already configured by an other client of the registry.
+func (o *optionClientValue) isSharedTransport(transport http.RoundTripper) bool {
+	return o.sharedTransport != nil && transport == http.RoundTripper(o.sharedTransport)
+}
+
+// configureTransport applies the http2, pool, proxy and TLS settings to the base transport.
+func (o *optionClientValue) configureTransport(client *http.Client) error {
+	if o.HTTP2 {
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("failed to cast transport to http.Transport")
+		}
+
+		transport.ForceAttemptHTTP2 = true
+		transport.Protocols = &protocols
+	}
+
+	// pool and timeouts, also used by http2 connections of the transport
+	if transport, ok := client.Transport.(*http.Transport); ok && transport == o.ownedTransport {
+		o.applyTransport(transport)
+	}
+
+	// make always after client creation
+	if o.Proxy != "" {
+		u, err := url.Parse(o.Proxy)
+		if err != nil {
+			return fmt.Errorf("failed to parse proxy url: %w", err)
+		}
+
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("failed to cast transport to http.Transport")
+		}
+
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	if o.TLSConfig != nil {
+		tlsClientConfig, err := o.TLSConfig.generate(o.tlsReloadError, o.tlsPinReport)
+		if err != nil {
+			return fmt.Errorf("failed to generate tls config: %w", err)
+		}
+
+		if transport, ok := client.Transport.(*http.Transport); ok {
+			transport.TLSClientConfig = tlsClientConfig
+		}
+	}
+
+	if o.InsecureSkipVerify {
+		if transport, ok := client.Transport.(*http.Transport); ok {
+			tlsClientConfig := transport.TLSClientConfig
+			if tlsClientConfig == nil {
+				tlsClientConfig = &tls.Config{
+					//nolint:gosec // user defined
+					InsecureSkipVerify: true,
+				}
+			} else {
+				tlsClientConfig.InsecureSkipVerify = true
+				// skip also the verification of the reloaded CA, pins are still checked with the leaf
+				var verifyPins pinCheck
+				if o.TLSConfig != nil {
+					verifyPins, _ = o.TLSConfig.pinVerifier(o.tlsPinReport)
+				}
+
+				tlsClientConfig.VerifyConnection = verifyConnectionPins(verifyPins)
+			}
+
+			transport.TLSClientConfig = tlsClientConfig
+		}
+	}
+
+	return nil
+}
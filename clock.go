@@ -0,0 +1,151 @@
+package klient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+// Clock is the time source of the client timers, default is the system clock.
+//
+// It is used by retry waits, RetryTimeout, server-sent events reconnects and download resumes,
+// klienttest.Clock is a controllable implementation for tests.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer created by the Clock.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer { return systemTimer{time.NewTimer(d)} }
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+func isSystemClock(clock Clock) bool {
+	_, ok := clock.(systemClock)
+
+	return clock == nil || ok
+}
+
+// sleepClock waits d on the clock, returns the context error if it is done before.
+func sleepClock(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	if clock == nil {
+		clock = systemClock{}
+	}
+
+	timer := clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// clockBackoff waits the backoff on the clock and returns 0 to retryablehttp.
+//
+// retryablehttp waits with the system timer, so the wait is moved here.
+func clockBackoff(ctx context.Context, clock Clock, backoff retryablehttp.Backoff) retryablehttp.Backoff {
+	return func(minWait, maxWait time.Duration, attemptNum int, resp *http.Response) time.Duration {
+		_ = sleepClock(ctx, clock, backoff(minWait, maxWait, attemptNum, resp))
+
+		return 0
+	}
+}
+
+// clockRetryTransport creates the retry client per request with the clock backoff.
+//
+// Backoff has no context argument and the response is nil on connection errors,
+// so the request context is bound to the backoff here.
+type clockRetryTransport struct {
+	newClient func(backoff retryablehttp.Backoff) *retryablehttp.Client
+	backoff   retryablehttp.Backoff
+	clock     Clock
+}
+
+func (t *clockRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	client := t.newClient(clockBackoff(req.Context(), t.clock, t.backoff))
+
+	return (&retryablehttp.RoundTripper{Client: client}).RoundTrip(req)
+}
+
+// contextWithTimeout is context.WithTimeout with the clock.
+func contextWithTimeout(ctx context.Context, clock Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if isSystemClock(clock) {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	c := &clockContext{Context: ctx, done: make(chan struct{})}
+	timer := clock.NewTimer(timeout)
+	stop := make(chan struct{})
+
+	go func() {
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			c.cancel(ctx.Err())
+		case <-timer.C():
+			c.cancel(context.DeadlineExceeded)
+		case <-stop:
+			c.cancel(context.Canceled)
+		}
+	}()
+
+	var once sync.Once
+
+	return c, func() { once.Do(func() { close(stop) }) }
+}
+
+// clockContext is done when the clock timer fires.
+//
+// Deadline is not reported, clock time is not comparable with the network deadlines.
+type clockContext struct {
+	context.Context
+
+	m    sync.Mutex
+	done chan struct{}
+	err  error
+}
+
+func (c *clockContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (c *clockContext) Done() <-chan struct{} { return c.done }
+
+func (c *clockContext) Err() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.err
+}
+
+func (c *clockContext) cancel(err error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
@@ -0,0 +1,300 @@
+package klient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingGzip    = "gzip"
+	EncodingZstd    = "zstd"
+	EncodingBrotli  = "br"
+	EncodingDeflate = "deflate"
+)
+
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// DefaultAcceptEncoding is the default Accept-Encoding values of the decompression transport.
+//
+// Requests without Accept-Encoding header are sent with "zstd, br, gzip" instead of the http.Transport's "gzip",
+// use WithAcceptEncoding(EncodingGzip) or WithDisableDecompression to keep the old header.
+var DefaultAcceptEncoding = []string{EncodingZstd, EncodingBrotli, EncodingGzip}
+
+// CompressionConfig is the request compression and response decompression configuration.
+type CompressionConfig struct {
+	// Encoding is the request body compression, gzip or zstd. Empty disables compression.
+	Encoding string `cfg:"encoding"`
+	// MinSize is the minimum request body size in bytes to compress.
+	MinSize int64 `cfg:"min_size"`
+	// AcceptEncoding is the list of accepted response encodings.
+	// Default is DefaultAcceptEncoding.
+	AcceptEncoding []string `cfg:"accept_encoding"`
+	// DisableDecompression disables the transparent response decompression.
+	DisableDecompression *bool `cfg:"disable_decompression"`
+}
+
+// requestCompressTransport compresses request bodies equal or bigger than minSize.
+//
+// Body is compressed while sending, GetBody of the request is wrapped so retries and redirects can replay it.
+// Bodies with unknown length are read up to minSize to decide.
+type requestCompressTransport struct {
+	base     http.RoundTripper
+	encoding string
+	minSize  int64
+}
+
+var _ http.RoundTripper = (*requestCompressTransport)(nil)
+
+func (t *requestCompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" ||
+		(req.ContentLength > 0 && req.ContentLength < t.minSize) {
+		return t.base.RoundTrip(req)
+	}
+
+	req2 := cloneRequest(req) // per RoundTripper contract
+	body := req.Body
+
+	if req.ContentLength <= 0 && t.minSize > 0 {
+		head := make([]byte, t.minSize)
+
+		n, err := io.ReadFull(req.Body, head)
+		switch {
+		case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+			// smaller than minSize, sent as is
+			_ = req.Body.Close()
+
+			head = head[:n]
+			req2.ContentLength = int64(n)
+			req2.Body = io.NopCloser(bytes.NewReader(head))
+			req2.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(head)), nil
+			}
+
+			return t.base.RoundTrip(req2)
+		case err != nil:
+			_ = req.Body.Close()
+
+			return nil, fmt.Errorf("read request body: %w", err)
+		}
+
+		body = readCloser{Reader: io.MultiReader(bytes.NewReader(head), req.Body), Closer: req.Body}
+	}
+
+	compressed, err := compressBody(t.encoding, body)
+	if err != nil {
+		_ = req.Body.Close()
+
+		return nil, err
+	}
+
+	req2.Header.Set("Content-Encoding", t.encoding)
+	req2.ContentLength = -1
+	req2.Body = compressed
+
+	if getBody := req.GetBody; getBody != nil {
+		req2.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+
+			return compressBody(t.encoding, body)
+		}
+	}
+
+	return t.base.RoundTrip(req2)
+}
+
+// compressBody returns the compressed body, compression runs while the returned body is read.
+//
+// Closing the returned body stops the compression and closes the body.
+func compressBody(encoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+
+	var (
+		w   io.WriteCloser
+		err error
+	)
+
+	switch encoding {
+	case EncodingGzip:
+		w = gzip.NewWriter(pw)
+	case EncodingZstd:
+		w, err = zstd.NewWriter(pw, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("zstd request body: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+	}
+
+	go func() {
+		_, err := io.Copy(w, body)
+		if errClose := w.Close(); err == nil {
+			err = errClose
+		}
+
+		_ = body.Close()
+		_ = pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// decompressTransport sets Accept-Encoding and decompresses the response body.
+//
+// Same as the http.Transport's gzip handling, it is skipped when the request
+// has Accept-Encoding or Range header.
+type decompressTransport struct {
+	base           http.RoundTripper
+	acceptEncoding string
+}
+
+var _ http.RoundTripper = (*decompressTransport)(nil)
+
+func (t *decompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" || req.Method == http.MethodHead {
+		return t.base.RoundTrip(req)
+	}
+
+	req2 := cloneRequest(req) // per RoundTripper contract
+	req2.Header.Set("Accept-Encoding", t.acceptEncoding)
+
+	resp, err := t.base.RoundTrip(req2)
+	if err != nil || resp == nil || resp.Body == nil {
+		return resp, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if !isSupportedEncoding(encoding) {
+		return resp, nil
+	}
+
+	body, err := newDecompressReader(encoding, resp.Body)
+	if err != nil {
+		DrainBody(resp.Body)
+
+		return nil, err
+	}
+
+	resp.Body = body
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+
+	return resp, nil
+}
+
+type decompressReader struct {
+	io.Reader
+	decoder io.Closer
+	body    io.ReadCloser
+}
+
+func (r *decompressReader) Close() error {
+	if r.decoder != nil {
+		_ = r.decoder.Close()
+	}
+
+	return r.body.Close()
+}
+
+// newDecompressReader returns the body decoder, decoder is created at first read
+// to not block on reading the header of an empty body.
+func newDecompressReader(encoding string, body io.ReadCloser) (io.ReadCloser, error) {
+	r := &decompressReader{body: body}
+
+	switch encoding {
+	case EncodingGzip:
+		r.Reader = &lazyReader{open: func() (io.Reader, error) {
+			zr, err := gzip.NewReader(body)
+			if err != nil {
+				return nil, err
+			}
+
+			r.decoder = zr
+
+			return zr, nil
+		}}
+	case EncodingDeflate:
+		r.Reader = &lazyReader{open: func() (io.Reader, error) {
+			zr, err := zlib.NewReader(body)
+			if err != nil {
+				return nil, err
+			}
+
+			r.decoder = zr
+
+			return zr, nil
+		}}
+	case EncodingZstd:
+		r.Reader = &lazyReader{open: func() (io.Reader, error) {
+			zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, fmt.Errorf("zstd response body: %w", err)
+			}
+
+			r.decoder = zstdCloser{zr}
+
+			return zr, nil
+		}}
+	case EncodingBrotli:
+		r.Reader = brotli.NewReader(body)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedEncoding, encoding)
+	}
+
+	return r, nil
+}
+
+func isSupportedEncoding(encoding string) bool {
+	switch encoding {
+	case EncodingGzip, EncodingDeflate, EncodingZstd, EncodingBrotli:
+		return true
+	}
+
+	return false
+}
+
+type zstdCloser struct {
+	*zstd.Decoder
+}
+
+func (z zstdCloser) Close() error {
+	z.Decoder.Close()
+
+	return nil
+}
+
+type lazyReader struct {
+	open func() (io.Reader, error)
+	r    io.Reader
+	err  error
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.r == nil && l.err == nil {
+		l.r, l.err = l.open()
+	}
+
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	return l.r.Read(p)
+}
@@ -0,0 +1,134 @@
+package klient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompression(t *testing.T) {
+	payload := strings.Repeat(`{"id":"123"}`, 100)
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body io.Reader = r.Body
+
+		switch r.Header.Get("Content-Encoding") {
+		case "zstd":
+			zr, err := zstd.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+			defer zr.Close()
+
+			body = zr
+		case "":
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		data, err := io.ReadAll(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		encoding := r.URL.Query().Get("encoding")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), encoding) {
+			w.WriteHeader(http.StatusNotAcceptable)
+
+			return
+		}
+
+		w.Header().Set("Content-Encoding", encoding)
+		w.Header().Set("X-Request-Encoding", r.Header.Get("Content-Encoding"))
+
+		var cw io.WriteCloser
+		switch encoding {
+		case "zstd":
+			cw, _ = zstd.NewWriter(w)
+		case "br":
+			cw = brotli.NewWriter(w)
+		case "gzip":
+			cw = gzip.NewWriter(w)
+		}
+
+		_, _ = cw.Write(data)
+		_ = cw.Close()
+	}))
+	defer httpServer.Close()
+
+	client, err := New(
+		WithBaseURL(httpServer.URL),
+		WithRetryMax(1),
+		WithCompression(EncodingZstd, 100),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name            string
+		encoding        string
+		body            string
+		stream          bool
+		requestEncoding string
+	}{
+		{name: "zstd", encoding: "zstd", body: payload, requestEncoding: "zstd"},
+		{name: "br", encoding: "br", body: payload, requestEncoding: "zstd"},
+		{name: "gzip", encoding: "gzip", body: "small", requestEncoding: ""},
+		{name: "stream", encoding: "zstd", body: payload, stream: true, requestEncoding: "zstd"},
+		{name: "small stream", encoding: "zstd", body: "small", stream: true, requestEncoding: ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var body io.Reader = bytes.NewBufferString(tt.body)
+			if tt.stream {
+				// unknown length without GetBody
+				body = io.MultiReader(strings.NewReader(tt.body))
+			}
+
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, "/echo?encoding="+tt.encoding, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := client.Do(req, func(resp *http.Response) error {
+				if err := UnexpectedResponse(resp); err != nil {
+					return err
+				}
+
+				if v := resp.Header.Get("X-Request-Encoding"); v != tt.requestEncoding {
+					t.Errorf("expected request encoding %q, got %q", tt.requestEncoding, v)
+				}
+
+				if resp.Header.Get("Content-Encoding") != "" {
+					t.Errorf("expected Content-Encoding to be removed")
+				}
+
+				data, err := io.ReadAll(resp.Body)
+				if err != nil {
+					return err
+				}
+
+				if string(data) != tt.body {
+					t.Errorf("unexpected body %q", data)
+				}
+
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
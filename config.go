@@ -1,30 +1,81 @@
 package klient
 
-import "time"
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+)
 
 type Config struct {
-	BaseURL string `cfg:"base_url"`
+	BaseURL string `cfg:"base_url" log:"url"`
 
 	Header map[string][]string `cfg:"header" log:"-"`
 
 	Timeout             time.Duration `cfg:"timeout"`
 	DisableBaseURLCheck *bool         `cfg:"disable_base_url_check"`
-	DisableEnvValues    *bool         `cfg:"disable_env_values"`
+	DisableEnvValues    *bool         `cfg:"disable_env_values" env:"-"`
 	InsecureSkipVerify  *bool         `cfg:"insecure_skip_verify"`
 
-	DisableRetry *bool         `cfg:"disable_retry"`
+	DisableRetry *bool         `cfg:"disable_retry" env:"DISABLE_RETRY,RETRY_DISABLE"`
 	RetryMax     int           `cfg:"retry_max"`
 	RetryWaitMin time.Duration `cfg:"retry_wait_min"`
 	RetryWaitMax time.Duration `cfg:"retry_wait_max"`
 	// RetryTimeout not works with http2
 	RetryTimeout time.Duration `cfg:"retry_timeout"`
 
+	Retry *RetryConfig `cfg:"retry"`
+
 	PooledClient *bool `cfg:"pooled_client"`
 
-	Proxy string `cfg:"proxy"`
+	MaxResponseBodySize int64 `cfg:"max_response_body_size"`
+
+	Transport *TransportConfig `cfg:"transport"`
+
+	Proxy string `cfg:"proxy" log:"url"`
 	HTTP2 *bool  `cfg:"http2"`
 
 	TLSConfig *TLSConfig `cfg:"tls"`
+
+	Compression *CompressionConfig `cfg:"compression"`
+
+	// BodyReplay is the handling of not rewindable request bodies for retries, see WithBodyReplay.
+	BodyReplay BodyReplayMode `cfg:"body_replay"`
+	// BodyReplayMemoryLimit is the in memory buffer size of buffer mode. Default is DefaultBodyReplayMemoryLimit.
+	BodyReplayMemoryLimit int64 `cfg:"body_replay_memory_limit"`
+}
+
+// RetryConfig is the retry policy and backoff configuration.
+type RetryConfig struct {
+	// EnabledStatusCodes are always retried, DisabledStatusCodes are never retried.
+	EnabledStatusCodes  []int `cfg:"enabled_status_codes"`
+	DisabledStatusCodes []int `cfg:"disabled_status_codes"`
+	// Methods limits the retried request methods, empty retries all methods.
+	Methods []string `cfg:"methods"`
+	// Backoff is exponential, linear_jitter, constant or decorrelated_jitter. Default is exponential.
+	Backoff BackoffType `cfg:"backoff"`
+	// Jitter is the random factor in [0, 1] of exponential and constant backoff.
+	Jitter float64 `cfg:"jitter"`
+	// Log enables logging of the retried responses. Default is true.
+	Log *bool `cfg:"log"`
+}
+
+// TransportConfig is the connection pool and timeout settings of the *http.Transport.
+//
+// Zero values keep the defaults. Only the transport created by klient is changed,
+// not the one given with WithHTTPClient or WithBaseTransport.
+type TransportConfig struct {
+	// MaxConnections is the maximum number of idle connections, also per host. Default is 100.
+	MaxConnections int `cfg:"max_connections"`
+	// MaxConnsPerHost limits the total connections per host. Default is no limit.
+	MaxConnsPerHost int `cfg:"max_conns_per_host"`
+
+	DialTimeout           time.Duration `cfg:"dial_timeout"`
+	KeepAlive             time.Duration `cfg:"keep_alive"`
+	IdleConnTimeout       time.Duration `cfg:"idle_conn_timeout"`
+	TLSHandshakeTimeout   time.Duration `cfg:"tls_handshake_timeout"`
+	ResponseHeaderTimeout time.Duration `cfg:"response_header_timeout"`
+	ExpectContinueTimeout time.Duration `cfg:"expect_continue_timeout"`
 }
 
 func (c Config) ToOption() OptionClientFn {
@@ -69,12 +120,76 @@ func (c Config) ToOption() OptionClientFn {
 			o.RetryTimeout = c.RetryTimeout
 		}
 
+		if c.Retry != nil {
+			if len(c.Retry.EnabledStatusCodes) > 0 {
+				o.OptionRetryFns = append(o.OptionRetryFns, OptionRetry.WithRetryEnabledStatusCodes(c.Retry.EnabledStatusCodes...))
+			}
+
+			if len(c.Retry.DisabledStatusCodes) > 0 {
+				o.OptionRetryFns = append(o.OptionRetryFns, OptionRetry.WithRetryDisabledStatusCodes(c.Retry.DisabledStatusCodes...))
+			}
+
+			if len(c.Retry.Methods) > 0 {
+				o.RetryMethods = c.Retry.Methods
+			}
+
+			if c.Retry.Backoff != "" {
+				o.RetryBackoff = c.Retry.Backoff
+			}
+
+			if c.Retry.Jitter != 0 {
+				o.RetryJitter = c.Retry.Jitter
+			}
+
+			if c.Retry.Log != nil {
+				o.RetryLog = *c.Retry.Log
+			}
+		}
+
 		if c.PooledClient != nil {
 			o.PooledClient = *c.PooledClient
 		}
 
+		if c.MaxResponseBodySize != 0 {
+			o.MaxResponseBodySize = c.MaxResponseBodySize
+		}
+
+		if c.Transport != nil {
+			if c.Transport.MaxConnections != 0 {
+				o.MaxConnections = c.Transport.MaxConnections
+			}
+
+			if c.Transport.MaxConnsPerHost != 0 {
+				o.MaxConnsPerHost = c.Transport.MaxConnsPerHost
+			}
+
+			if c.Transport.DialTimeout != 0 {
+				o.DialTimeout = c.Transport.DialTimeout
+			}
+
+			if c.Transport.KeepAlive != 0 {
+				o.KeepAlive = c.Transport.KeepAlive
+			}
+
+			if c.Transport.IdleConnTimeout != 0 {
+				o.IdleConnTimeout = c.Transport.IdleConnTimeout
+			}
+
+			if c.Transport.TLSHandshakeTimeout != 0 {
+				o.TLSHandshakeTimeout = c.Transport.TLSHandshakeTimeout
+			}
+
+			if c.Transport.ResponseHeaderTimeout != 0 {
+				o.ResponseHeaderTimeout = c.Transport.ResponseHeaderTimeout
+			}
+
+			if c.Transport.ExpectContinueTimeout != 0 {
+				o.ExpectContinueTimeout = c.Transport.ExpectContinueTimeout
+			}
+		}
+
 		if len(c.Header) > 0 {
-			o.Header = c.Header
+			o.Header = http.Header(c.Header).Clone()
 		}
 
 		if c.Proxy != "" {
@@ -86,13 +201,51 @@ func (c Config) ToOption() OptionClientFn {
 		}
 
 		if c.TLSConfig != nil {
-			o.TLSConfig = c.TLSConfig
+			tlsConfig := *c.TLSConfig
+			if o.TLSConfig != nil {
+				// set fields win, the others like KLIENT_TLS_* values are kept
+				mergeValue(reflect.ValueOf(&tlsConfig).Elem(), reflect.ValueOf(*o.TLSConfig))
+			}
+
+			o.TLSConfig = &tlsConfig
+		}
+
+		if c.Compression != nil {
+			if c.Compression.Encoding != "" {
+				o.CompressionEncoding = c.Compression.Encoding
+			}
+
+			if c.Compression.MinSize != 0 {
+				o.CompressionMinSize = c.Compression.MinSize
+			}
+
+			if len(c.Compression.AcceptEncoding) > 0 {
+				o.AcceptEncoding = c.Compression.AcceptEncoding
+			}
+
+			if c.Compression.DisableDecompression != nil {
+				o.DisableDecompression = *c.Compression.DisableDecompression
+			}
+		}
+
+		if c.BodyReplay != "" {
+			o.BodyReplay = c.BodyReplay
+		}
+
+		if c.BodyReplayMemoryLimit != 0 {
+			o.BodyReplayMemoryLimit = c.BodyReplayMemoryLimit
 		}
 	}
 }
 
 // New creates a new client with the configuration.
 //   - Add pre defined options
+//
+// Configuration is validated before creating the client, see Config.Validate.
 func (c *Config) New(options ...OptionClientFn) (*Client, error) {
-	return New(append(options, c.ToOption())...)
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return newClient(options, c.ToOption())
 }
@@ -0,0 +1,471 @@
+package klient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrDownloadChanged  = errors.New("download resource changed")
+	ErrDownloadChecksum = errors.New("download checksum mismatch")
+	ErrDownloadRange    = errors.New("download unexpected range")
+)
+
+type optionDownloadValue struct {
+	SHA256   string
+	Parallel int
+	Header   http.Header
+}
+
+type OptionDownloadFn func(*optionDownloadValue)
+
+type OptionDownloadHolder struct{}
+
+var OptionDownload = OptionDownloadHolder{}
+
+// WithSHA256 verifies the downloaded content with the hex encoded SHA-256 checksum.
+func (OptionDownloadHolder) WithSHA256(checksum string) OptionDownloadFn {
+	return func(o *optionDownloadValue) {
+		o.SHA256 = strings.ToLower(checksum)
+	}
+}
+
+// WithParallel downloads with n parallel range requests when the server supports ranges.
+//
+// Writer should be an io.ReaderAt to verify checksum of the parallel download.
+func (OptionDownloadHolder) WithParallel(n int) OptionDownloadFn {
+	return func(o *optionDownloadValue) {
+		o.Parallel = n
+	}
+}
+
+// WithHeader sets extra headers of the download requests.
+func (OptionDownloadHolder) WithHeader(header http.Header) OptionDownloadFn {
+	return func(o *optionDownloadValue) {
+		o.Header = header
+	}
+}
+
+// DownloadFile downloads the url to the file in path, see Download.
+//
+// File is truncated to the downloaded size and removed when the checksum does not match.
+func (c *Client) DownloadFile(ctx context.Context, url, path string, opts ...OptionDownloadFn) (int64, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("open download file: %w", err)
+	}
+
+	n, err := c.Download(ctx, url, f, opts...)
+	if err == nil {
+		err = f.Truncate(n)
+	}
+
+	if errClose := f.Close(); errClose != nil && err == nil {
+		err = errClose
+	}
+
+	if errors.Is(err, ErrDownloadChecksum) {
+		_ = os.Remove(path)
+	}
+
+	return n, err
+}
+
+// Download downloads the url to w and returns the size of the content.
+//
+// After connection failures the download resumes with Range requests,
+// the If-Range header with ETag or Last-Modified guarantees that content
+// of different versions never stitched together.
+// Requests go through the client's transport chain with the middlewares, but not the retry;
+// failures retried with the client's RetryMax and backoff settings, not retried when retry is disabled.
+// The client's whole request Timeout and WithMaxResponseBodySize are not applied.
+// Accept-Encoding is identity unless set with OptionDownload.WithHeader, content is written as sent.
+func (c *Client) Download(ctx context.Context, url string, w io.WriterAt, opts ...OptionDownloadFn) (int64, error) {
+	o := optionDownloadValue{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	stream := c.transport.state.Load().stream
+
+	d := &download{
+		stream: stream,
+		client: &http.Client{Transport: stream.Transport},
+		url:    url,
+		w:      w,
+		header: o.Header,
+	}
+
+	readerAt, isReaderAt := w.(io.ReaderAt)
+
+	var (
+		size int64
+		err  error
+	)
+
+	if o.Parallel > 1 {
+		if o.SHA256 != "" && !isReaderAt {
+			return 0, fmt.Errorf("%w: parallel download requires io.ReaderAt writer", ErrDownloadChecksum)
+		}
+
+		size, err = d.parallel(ctx, o.Parallel)
+	} else {
+		if o.SHA256 != "" && !isReaderAt {
+			d.hash = sha256.New()
+		}
+
+		size, err = d.fetch(ctx, 0, -1, true)
+	}
+
+	if err != nil {
+		return size, err
+	}
+
+	if o.SHA256 != "" {
+		h := d.hash
+		if h == nil {
+			h = sha256.New()
+			if _, err := io.Copy(h, io.NewSectionReader(readerAt, 0, size)); err != nil {
+				return size, fmt.Errorf("read downloaded content: %w", err)
+			}
+		}
+
+		if sum := hex.EncodeToString(h.Sum(nil)); sum != o.SHA256 {
+			return size, fmt.Errorf("%w: expected %s, got %s", ErrDownloadChecksum, o.SHA256, sum)
+		}
+	}
+
+	return size, nil
+}
+
+type download struct {
+	stream streamValue
+	client *http.Client
+	url    string
+	w      io.WriterAt
+	header http.Header
+
+	// hash is the streaming checksum of the sequential download.
+	hash hash.Hash
+
+	m sync.Mutex
+	// validator is the ETag or Last-Modified value used with If-Range.
+	validator string
+}
+
+func (d *download) getValidator() string {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	return d.validator
+}
+
+func (d *download) setValidator(resp *http.Response) {
+	d.m.Lock()
+	defer d.m.Unlock()
+
+	d.validator = responseValidator(resp)
+}
+
+// parallel probes the size of the content and downloads ranges in parallel.
+func (d *download) parallel(ctx context.Context, n int) (int64, error) {
+	resp, err := d.do(ctx, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	DrainBody(resp.Body)
+	d.setValidator(resp)
+
+	_, _, size, err := contentRange(resp)
+	if resp.StatusCode != http.StatusPartialContent || err != nil || size < 0 || d.getValidator() == "" {
+		// range not supported, continue with a single request
+		return d.fetch(ctx, 0, -1, true)
+	}
+
+	chunk := (size + int64(n) - 1) / int64(n)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		errPar  error
+	)
+
+	for start := int64(0); start < size; start += chunk {
+		end := min(start+chunk, size) - 1
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if _, err := d.fetch(ctx, start, end, false); err != nil {
+				errOnce.Do(func() {
+					errPar = err
+					cancel()
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return size, errPar
+}
+
+// fetch downloads the [start, end] range, end -1 means until the end of the content.
+//
+// When restartable is true a changed resource restarts the download from the start,
+// otherwise ErrDownloadChanged is returned.
+func (d *download) fetch(ctx context.Context, start, end int64, restartable bool) (int64, error) {
+	offset := start
+
+	for attempt := 0; ; {
+		resp, err := d.do(ctx, offset, end)
+		if err == nil {
+			var written int64
+			written, err = d.write(resp, start, &offset, end, restartable)
+			if err == nil {
+				return offset - start, nil
+			}
+
+			if written > 0 {
+				attempt = 0
+			}
+		}
+
+		var errPermanent *downloadError
+		if errors.As(err, &errPermanent) {
+			return offset - start, errPermanent.err
+		}
+
+		if ctx.Err() != nil {
+			return offset - start, ctx.Err()
+		}
+
+		if attempt >= d.stream.RetryMax {
+			return offset - start, err
+		}
+
+		d.stream.logWarn("download interrupted, resuming", err)
+
+		wait := d.stream.Backoff(d.stream.RetryWaitMin, d.stream.RetryWaitMax, attempt, resp)
+		if err := sleepClock(ctx, d.stream.Clock, wait); err != nil {
+			return offset - start, err
+		}
+
+		attempt++
+	}
+}
+
+// write copies the response body to the writer starting from offset.
+//
+// Returns the written bytes of this response.
+func (d *download) write(resp *http.Response, start int64, offset *int64, end int64, restartable bool) (int64, error) {
+	defer DrainBody(resp.Body)
+
+	size := int64(-1)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if *offset != start || start != 0 || end >= 0 {
+			if !restartable {
+				return 0, &downloadError{err: ErrDownloadChanged}
+			}
+
+			// resource changed or range not supported, restart
+			*offset = start
+		}
+
+		if d.hash != nil {
+			d.hash.Reset()
+		}
+
+		size = resp.ContentLength
+	case http.StatusPartialContent:
+		rangeStart, _, total, err := contentRange(resp)
+		if err != nil {
+			return 0, &downloadError{err: err}
+		}
+
+		if rangeStart != *offset {
+			return 0, &downloadError{err: fmt.Errorf("%w: expected start %d, got %d", ErrDownloadRange, *offset, rangeStart)}
+		}
+
+		size = total
+	case http.StatusRequestedRangeNotSatisfiable:
+		// already have all content
+		if _, _, total, err := contentRange(resp); err == nil && total == *offset {
+			return 0, nil
+		}
+
+		return 0, &downloadError{err: ErrResponse(resp)}
+	}
+
+	if d.getValidator() == "" || resp.StatusCode == http.StatusOK {
+		d.setValidator(resp)
+	}
+
+	var dst io.Writer = io.NewOffsetWriter(d.w, *offset)
+	if d.hash != nil {
+		dst = io.MultiWriter(dst, d.hash)
+	}
+
+	written, err := io.Copy(dst, resp.Body)
+	*offset += written
+
+	if err != nil {
+		return written, err
+	}
+
+	if end < 0 && size >= 0 && *offset < size {
+		return written, io.ErrUnexpectedEOF
+	}
+
+	if end >= 0 && *offset <= end {
+		return written, io.ErrUnexpectedEOF
+	}
+
+	return written, nil
+}
+
+// do sends the request for [offset, end] range, end -1 means until the end of the content.
+func (d *download) do(ctx context.Context, offset, end int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctxWithStream(ctx), http.MethodGet, d.url, nil)
+	if err != nil {
+		return nil, &downloadError{err: fmt.Errorf("%w: %w", ErrCreateRequest, err)}
+	}
+
+	// decompression changes the length and the ranges, content is downloaded as is
+	req.Header.Set("Accept-Encoding", "identity")
+
+	for k, v := range d.header {
+		req.Header[k] = v
+	}
+
+	validator := d.getValidator()
+	if offset > 0 || end >= 0 {
+		if offset > 0 && validator == "" {
+			// cannot resume safely without a validator
+			offset = 0
+		}
+
+		if end >= 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, end))
+		} else if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequest, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK, resp.StatusCode == http.StatusPartialContent,
+		resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		if validator != "" && resp.StatusCode == http.StatusPartialContent {
+			if v := responseValidator(resp); v != "" && v != validator {
+				DrainBody(resp.Body)
+
+				return nil, &downloadError{err: ErrDownloadChanged}
+			}
+		}
+
+		return resp, nil
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= http.StatusInternalServerError:
+		defer DrainBody(resp.Body)
+
+		return nil, ErrResponse(resp)
+	default:
+		defer DrainBody(resp.Body)
+
+		return nil, &downloadError{err: ErrResponse(resp)}
+	}
+}
+
+// downloadError is a not retryable download error.
+type downloadError struct {
+	err error
+}
+
+func (e *downloadError) Error() string {
+	return e.err.Error()
+}
+
+func (e *downloadError) Unwrap() error {
+	return e.err
+}
+
+// responseValidator returns the strong ETag or Last-Modified value usable with If-Range.
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return resp.Header.Get("Last-Modified")
+}
+
+// contentRange parses the Content-Range header, total is -1 when unknown.
+func contentRange(resp *http.Response) (int64, int64, int64, error) {
+	v := resp.Header.Get("Content-Range")
+
+	unit, rest, ok := strings.Cut(v, " ")
+	if !ok || unit != "bytes" {
+		return 0, 0, 0, fmt.Errorf("%w: invalid content range %q", ErrDownloadRange, v)
+	}
+
+	rangeValue, totalValue, ok := strings.Cut(rest, "/")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("%w: invalid content range %q", ErrDownloadRange, v)
+	}
+
+	total := int64(-1)
+	if totalValue != "*" {
+		var err error
+		if total, err = strconv.ParseInt(totalValue, 10, 64); err != nil {
+			return 0, 0, 0, fmt.Errorf("%w: invalid content range %q", ErrDownloadRange, v)
+		}
+	}
+
+	// unsatisfied range "*/total"
+	if rangeValue == "*" {
+		return 0, 0, total, nil
+	}
+
+	startValue, endValue, ok := strings.Cut(rangeValue, "-")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("%w: invalid content range %q", ErrDownloadRange, v)
+	}
+
+	start, err := strconv.ParseInt(startValue, 10, 64)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("%w: invalid content range %q", ErrDownloadRange, v)
+	}
+
+	end, err := strconv.ParseInt(endValue, 10, 64)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("%w: invalid content range %q", ErrDownloadRange, v)
+	}
+
+	return start, end, total, nil
+}
@@ -0,0 +1,236 @@
+package klient
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type bufferWriterAt struct {
+	m   sync.Mutex
+	buf []byte
+}
+
+func (b *bufferWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	if end := int(off) + len(p); end > len(b.buf) {
+		b.buf = append(b.buf, make([]byte, end-len(b.buf))...)
+	}
+
+	return copy(b.buf[off:], p), nil
+}
+
+func TestClient_Download(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 4096)
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	var (
+		requests atomic.Int32
+		ranges   atomic.Int32
+	)
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.Header.Get("Range") == "" {
+			// drop the connection in the middle of the content
+			w.Header().Set("Content-Length", "65536")
+			_, _ = w.Write(content[:1000])
+			w.(http.Flusher).Flush()
+
+			panic(http.ErrAbortHandler)
+		}
+
+		ranges.Add(1)
+		http.ServeContent(w, r, "content", time.Time{}, bytes.NewReader(content))
+	}))
+	defer httpServer.Close()
+
+	client, err := New(
+		WithBaseURL(httpServer.URL),
+		WithRetryWaitMin(10*time.Millisecond),
+		WithRetryWaitMax(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("resume", func(t *testing.T) {
+		w := &bufferWriterAt{}
+
+		n, err := client.Download(t.Context(), "/file", w, OptionDownload.WithSHA256(checksum))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != int64(len(content)) || !bytes.Equal(w.buf, content) {
+			t.Fatalf("unexpected content with size %d", n)
+		}
+	})
+
+	t.Run("parallel file", func(t *testing.T) {
+		ranges.Store(0)
+		path := filepath.Join(t.TempDir(), "file")
+
+		n, err := client.DownloadFile(t.Context(), "/file", path, OptionDownload.WithSHA256(checksum), OptionDownload.WithParallel(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n != int64(len(content)) || !bytes.Equal(got, content) {
+			t.Fatalf("unexpected content with size %d", n)
+		}
+
+		// probe + 4 parts
+		if v := ranges.Load(); v != 5 {
+			t.Fatalf("expected 5 range requests, got %d", v)
+		}
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+
+		_, err := client.DownloadFile(t.Context(), "/file", path, OptionDownload.WithSHA256(strings.Repeat("0", 64)))
+		if !errors.Is(err, ErrDownloadChecksum) {
+			t.Fatalf("expected checksum error, got %v", err)
+		}
+
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected file to be removed, got %v", err)
+		}
+	})
+}
+
+func TestClient_DownloadTransport(t *testing.T) {
+	var requests atomic.Int32
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.Header.Get("Accept-Encoding") != "identity" || r.Header.Get("X-Middleware") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Length", "2048")
+		_, _ = w.Write(bytes.Repeat([]byte("a"), 100))
+		w.(http.Flusher).Flush()
+
+		panic(http.ErrAbortHandler)
+	}))
+	defer httpServer.Close()
+
+	client, err := New(
+		WithBaseURL(httpServer.URL),
+		WithDisableRetry(true),
+		WithRoundTripper(func(_ context.Context, base http.RoundTripper) (http.RoundTripper, error) {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				r = r.Clone(r.Context())
+				r.Header.Set("X-Middleware", "true")
+
+				return base.RoundTrip(r)
+			}), nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Download(t.Context(), "/file", &bufferWriterAt{}); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected unexpected EOF, got %v", err)
+	}
+
+	// not resumed when retry is disabled
+	if v := requests.Load(); v != 1 {
+		t.Fatalf("expected 1 request, got %d", v)
+	}
+}
+
+func TestClient_DownloadMaxResponseBodySize(t *testing.T) {
+	content := bytes.Repeat([]byte("a"), 4096)
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "content", time.Time{}, bytes.NewReader(content))
+	}))
+	defer httpServer.Close()
+
+	client, err := New(WithBaseURL(httpServer.URL), WithMaxResponseBodySize(100), WithDisableRetry(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &bufferWriterAt{}
+	if n, err := client.Download(t.Context(), "/file", w); err != nil || n != int64(len(content)) {
+		t.Fatalf("expected download without the client limit, got %d %v", n, err)
+	}
+
+	ctx := CtxWithMaxResponseBodySize(t.Context(), 100)
+	if _, err := client.Download(ctx, "/file", &bufferWriterAt{}); !errors.Is(err, ErrResponseTooLarge) {
+		t.Fatalf("expected limit of the context, got %v", err)
+	}
+}
+
+func TestClient_DownloadChanged(t *testing.T) {
+	var version atomic.Int32
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := version.Add(1)
+		content := bytes.Repeat([]byte{byte('a' + v)}, 2048)
+
+		w.Header().Set("ETag", `"v`+string(rune('0'+v))+`"`)
+
+		if v == 1 {
+			w.Header().Set("Content-Length", "2048")
+			_, _ = w.Write(content[:100])
+			w.(http.Flusher).Flush()
+
+			panic(http.ErrAbortHandler)
+		}
+
+		http.ServeContent(w, r, "content", time.Time{}, bytes.NewReader(content))
+	}))
+	defer httpServer.Close()
+
+	client, err := New(
+		WithBaseURL(httpServer.URL),
+		WithRetryWaitMin(10*time.Millisecond),
+		WithRetryWaitMax(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &bufferWriterAt{}
+	if _, err := client.Download(t.Context(), "/file", w); err != nil {
+		t.Fatal(err)
+	}
+
+	// If-Range mismatch returns the full new version
+	if want := bytes.Repeat([]byte{'c'}, 2048); !bytes.Equal(w.buf, want) {
+		t.Fatalf("expected only the new version, got %q", w.buf[:200])
+	}
+}
@@ -0,0 +1,166 @@
+package klient
+
+import (
+	"cmp"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// MaskedValue replaces the sensitive values in Config.Masked.
+const MaskedValue = "***"
+
+// Effective returns the configuration the client of the name is created with.
+//
+// Options are the ones given to New, they are merged with the config, env values and defaults
+// with the same precedence as New. Values without a Config field like WithBackoff are not included.
+func (c Config) Effective(name string, options ...OptionClientFn) (Config, error) {
+	opts := append([]OptionClientFn{WithName(name)}, options...)
+
+	o, _, err := newClientOption(clientOptions(opts, c.ToOption()))
+	if err != nil {
+		return Config{}, err
+	}
+
+	return o.config(), nil
+}
+
+// config returns the option values as Config, zero transport values are replaced with the defaults in use.
+func (o *optionClientValue) config() Config {
+	var retry optionRetryValue
+	for _, fn := range o.OptionRetryFns {
+		fn(&retry)
+	}
+
+	return Config{
+		BaseURL:             o.BaseURL,
+		Header:              o.Header,
+		Timeout:             o.Timeout,
+		DisableBaseURLCheck: &o.DisableBaseURLCheck,
+		DisableEnvValues:    &o.DisableEnvValues,
+		InsecureSkipVerify:  &o.InsecureSkipVerify,
+		DisableRetry:        &o.DisableRetry,
+		RetryMax:            o.RetryMax,
+		RetryWaitMin:        o.RetryWaitMin,
+		RetryWaitMax:        o.RetryWaitMax,
+		RetryTimeout:        o.RetryTimeout,
+		Retry: &RetryConfig{
+			EnabledStatusCodes:  retry.EnabledStatusCodes,
+			DisabledStatusCodes: retry.DisabledStatusCodes,
+			Methods:             o.RetryMethods,
+			Backoff:             cmp.Or(o.RetryBackoff, BackoffExponential),
+			Jitter:              o.RetryJitter,
+			Log:                 &o.RetryLog,
+		},
+		PooledClient:        &o.PooledClient,
+		MaxResponseBodySize: o.MaxResponseBodySize,
+		Transport: &TransportConfig{
+			MaxConnections:        o.MaxConnections,
+			MaxConnsPerHost:       o.MaxConnsPerHost,
+			DialTimeout:           cmp.Or(o.DialTimeout, defaultDialTimeout),
+			KeepAlive:             cmp.Or(o.KeepAlive, defaultKeepAlive),
+			IdleConnTimeout:       o.IdleConnTimeout,
+			TLSHandshakeTimeout:   o.TLSHandshakeTimeout,
+			ResponseHeaderTimeout: o.ResponseHeaderTimeout,
+			ExpectContinueTimeout: o.ExpectContinueTimeout,
+		},
+		Proxy:     o.Proxy,
+		HTTP2:     &o.HTTP2,
+		TLSConfig: o.TLSConfig,
+		Compression: &CompressionConfig{
+			Encoding:             o.CompressionEncoding,
+			MinSize:              o.CompressionMinSize,
+			AcceptEncoding:       o.AcceptEncoding,
+			DisableDecompression: &o.DisableDecompression,
+		},
+		BodyReplay:            o.BodyReplay,
+		BodyReplayMemoryLimit: cmp.Or(o.BodyReplayMemoryLimit, DefaultBodyReplayMemoryLimit),
+	}
+}
+
+// Masked returns the configuration as map of the `cfg` tags for logs, sensitive values are masked.
+//
+// Fields tagged `log:"-"` like headers and TLS files are replaced with MaskedValue,
+// passwords of `log:"url"` fields are removed. Durations are strings and unset values are omitted.
+//
+//	cfg, err := config.Effective("users")
+//	if err != nil {
+//		return err
+//	}
+//
+//	log.Info().Interface("config", cfg.Masked()).Msg("klient config")
+func (c Config) Masked() map[string]any {
+	return maskedStruct(reflect.ValueOf(c))
+}
+
+func maskedStruct(v reflect.Value) map[string]any {
+	m := make(map[string]any, v.NumField())
+
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+
+		name, _, _ := strings.Cut(field.Tag.Get("cfg"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+
+		value := v.Field(i)
+		if (value.Kind() == reflect.Pointer || value.Kind() == reflect.Map || value.Kind() == reflect.Slice) && value.IsNil() {
+			continue
+		}
+
+		switch field.Tag.Get("log") {
+		case "-":
+			if masked := maskedValue(value); masked != nil {
+				m[name] = masked
+			}
+
+			continue
+		case "url":
+			if u, err := url.Parse(value.String()); err == nil && value.String() != "" {
+				m[name] = u.Redacted()
+			} else if value.String() != "" {
+				m[name] = MaskedValue
+			}
+
+			continue
+		}
+
+		if value.Kind() == reflect.Pointer {
+			value = value.Elem()
+		}
+
+		switch {
+		case value.Type() == reflect.TypeFor[time.Duration]():
+			m[name] = time.Duration(value.Int()).String()
+		case value.Kind() == reflect.Struct:
+			m[name] = maskedStruct(value)
+		case value.Kind() == reflect.String:
+			m[name] = value.String()
+		default:
+			m[name] = value.Interface()
+		}
+	}
+
+	return m
+}
+
+// maskedValue keeps the keys of maps to show what is set, nil for empty values.
+func maskedValue(v reflect.Value) any {
+	if v.IsZero() {
+		return nil
+	}
+
+	if v.Kind() == reflect.Map {
+		m := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			m[fmt.Sprint(iter.Key().Interface())] = MaskedValue
+		}
+
+		return m
+	}
+
+	return MaskedValue
+}
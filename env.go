@@ -0,0 +1,305 @@
+package klient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrEnvValue = errors.New("invalid env value")
+	ErrEnvName  = errors.New("invalid env name")
+)
+
+// legacyEnvNames are ignored when malformed like before ConfigFromEnv.
+var legacyEnvNames = []string{EnvKlientInsecureSkipVerify, EnvKlientTimeout, EnvKlientRetryDisable}
+
+var typeDuration = reflect.TypeFor[time.Duration]()
+
+// ConfigFromEnv returns the configuration from KLIENT_* env values.
+//
+// Env names are the upper case `cfg` tags of the Config fields, nested fields
+// are joined with underscore, e.g. KLIENT_RETRY_MAX, KLIENT_TLS_CA_FILE.
+// With a name, KLIENT_<NAME>_* values override the global ones, e.g. KLIENT_PAYMENTS_RETRY_MAX.
+//
+// Slices are comma separated and maps are JSON encoded values.
+// Names colliding with the global env names, like "tls" or "retry", return ErrEnvName.
+func ConfigFromEnv(name string) (Config, error) {
+	var cfg Config
+
+	if err := checkEnvName(name); err != nil {
+		return Config{}, err
+	}
+
+	if _, err := loadEnvStruct(EnvKlientPrefix, reflect.ValueOf(&cfg).Elem()); err != nil {
+		return Config{}, err
+	}
+
+	if name != "" {
+		if _, err := loadEnvStruct(EnvPrefix(name), reflect.ValueOf(&cfg).Elem()); err != nil {
+			return Config{}, err
+		}
+	}
+
+	return cfg, nil
+}
+
+// EnvPrefix returns the env prefix of the named client, KLIENT_<NAME>_.
+//
+// Name is upper cased and characters other than letters and digits are replaced with underscore.
+func EnvPrefix(name string) string {
+	if name == "" {
+		return EnvKlientPrefix
+	}
+
+	return EnvKlientPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+
+		return '_'
+	}, name) + "_"
+}
+
+// checkEnvName reports an error when KLIENT_<NAME>_ is also the prefix of a global env value.
+func checkEnvName(name string) error {
+	if name == "" {
+		return nil
+	}
+
+	prefix := strings.TrimPrefix(EnvPrefix(name), EnvKlientPrefix)
+	for _, key := range envKeys("", reflect.TypeFor[Config]()) {
+		if strings.HasPrefix(key, prefix) {
+			return fmt.Errorf("%w: client name %q collides with %s%s", ErrEnvName, name, EnvKlientPrefix, key)
+		}
+	}
+
+	return nil
+}
+
+// envKeys returns the env names of the struct fields, nested ones joined with underscore.
+func envKeys(prefix string, t reflect.Type) []string {
+	var keys []string
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		for j, name := range envNames(field) {
+			if ft.Kind() == reflect.Struct {
+				if j == 0 {
+					keys = append(keys, envKeys(prefix+name+"_", ft)...)
+				}
+
+				continue
+			}
+
+			keys = append(keys, prefix+name)
+		}
+	}
+
+	return keys
+}
+
+// loadEnvStruct sets the fields of the struct from env values, reports whether any value is set.
+func loadEnvStruct(prefix string, v reflect.Value) (bool, error) {
+	var (
+		set  bool
+		errs []error
+	)
+
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		names := envNames(field)
+		if len(names) == 0 {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		// nested struct
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+
+		if ft.Kind() == reflect.Struct {
+			nested := reflect.New(ft).Elem()
+			if fv.Kind() == reflect.Pointer && !fv.IsNil() {
+				nested = fv.Elem()
+			} else if fv.Kind() == reflect.Struct {
+				nested = fv
+			}
+
+			ok, err := loadEnvStruct(prefix+names[0]+"_", nested)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			if ok && fv.Kind() == reflect.Pointer && fv.IsNil() {
+				ptr := reflect.New(ft)
+				ptr.Elem().Set(nested)
+				fv.Set(ptr)
+			}
+
+			set = set || ok
+
+			continue
+		}
+
+		for _, name := range names {
+			value, ok := os.LookupEnv(prefix + name)
+			if !ok {
+				continue
+			}
+
+			if err := setEnvValue(fv, value); err != nil {
+				if !slices.Contains(legacyEnvNames, prefix+name) {
+					errs = append(errs, fmt.Errorf("%w %s: %w", ErrEnvValue, prefix+name, err))
+				}
+
+				break
+			}
+
+			set = true
+
+			break
+		}
+	}
+
+	return set, errors.Join(errs...)
+}
+
+// envNames returns the env names of the field, `env` tag could list alternative names, "-" skips the field.
+func envNames(field reflect.StructField) []string {
+	if tag, ok := field.Tag.Lookup("env"); ok {
+		if tag == "-" {
+			return nil
+		}
+
+		return strings.Split(tag, ",")
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("cfg"), ",")
+	if name == "" || name == "-" {
+		return nil
+	}
+
+	return []string{strings.ToUpper(name)}
+}
+
+func setEnvValue(fv reflect.Value, value string) error {
+	if fv.Kind() == reflect.Pointer {
+		ptr := reflect.New(fv.Type().Elem())
+		if err := setEnvValue(ptr.Elem(), value); err != nil {
+			return err
+		}
+
+		fv.Set(ptr)
+
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Map:
+		ptr := reflect.New(fv.Type())
+		if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+			return err
+		}
+
+		fv.Set(ptr.Elem())
+
+		return nil
+	case reflect.Slice:
+		var parts []string
+		for part := range strings.SplitSeq(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+
+		slice := reflect.MakeSlice(fv.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := setEnvScalar(slice.Index(i), part); err != nil {
+				return err
+			}
+		}
+
+		fv.Set(slice)
+
+		return nil
+	}
+
+	return setEnvScalar(fv, value)
+}
+
+func setEnvScalar(fv reflect.Value, value string) error {
+	if fv.Type() == typeDuration {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		fv.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+
+	return nil
+}
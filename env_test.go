@@ -0,0 +1,197 @@
+package klient
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("KLIENT_BASE_URL", "http://global")
+	t.Setenv("KLIENT_RETRY_MAX", "7")
+	t.Setenv("KLIENT_RETRY_DISABLE", "false")
+	t.Setenv("KLIENT_HEADER", `{"X-Info":["global"]}`)
+	t.Setenv("KLIENT_COMPRESSION_ACCEPT_ENCODING", "gzip, br")
+	t.Setenv("KLIENT_PAYMENTS_BASE_URL", "http://payments")
+	t.Setenv("KLIENT_PAYMENTS_RETRY_WAIT_MIN", "2s")
+	t.Setenv("KLIENT_PAYMENTS_TLS_CA_FILE", "/ca.pem")
+	t.Setenv("KLIENT_PAYMENTS_DISABLE_ENV_VALUES", "true")
+
+	disableRetry := false
+
+	global, err := ConfigFromEnv("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(global, Config{
+		BaseURL:      "http://global",
+		Header:       map[string][]string{"X-Info": {"global"}},
+		DisableRetry: &disableRetry,
+		RetryMax:     7,
+		Compression:  &CompressionConfig{AcceptEncoding: []string{"gzip", "br"}},
+	}); diff != nil {
+		t.Error(diff)
+	}
+
+	named, err := ConfigFromEnv("payments")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(named, Config{
+		BaseURL:      "http://payments",
+		Header:       map[string][]string{"X-Info": {"global"}},
+		DisableRetry: &disableRetry,
+		RetryMax:     7,
+		RetryWaitMin: 2 * time.Second,
+		TLSConfig:    &TLSConfig{CAFile: "/ca.pem"},
+		Compression:  &CompressionConfig{AcceptEncoding: []string{"gzip", "br"}},
+	}); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestConfigFromEnvInvalid(t *testing.T) {
+	t.Setenv("KLIENT_BILLING_RETRY_MAX", "many")
+
+	if _, err := ConfigFromEnv("billing"); !errors.Is(err, ErrEnvValue) {
+		t.Fatalf("expected env value error, got %v", err)
+	}
+
+	if _, err := New(WithName("billing"), WithDisableBaseURLCheck(true)); !errors.Is(err, ErrEnvValue) {
+		t.Fatalf("expected env value error, got %v", err)
+	}
+}
+
+func TestConfigFromEnvLegacy(t *testing.T) {
+	t.Setenv("KLIENT_TIMEOUT", "30")
+	t.Setenv("KLIENT_RETRY_DISABLE", "yes")
+
+	if _, err := New(WithDisableBaseURLCheck(true)); err != nil {
+		t.Fatalf("expected malformed legacy values to be ignored, got %v", err)
+	}
+}
+
+func TestConfigFromEnvName(t *testing.T) {
+	for _, name := range []string{"tls", "retry", "Transport", "disable"} {
+		if _, err := ConfigFromEnv(name); !errors.Is(err, ErrEnvName) {
+			t.Errorf("%s: expected env name error, got %v", name, err)
+		}
+	}
+
+	if _, err := ConfigFromEnv("payments"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigTLSEnv(t *testing.T) {
+	t.Setenv("KLIENT_TLS_MIN_VERSION", "1.2")
+	t.Setenv("KLIENT_TLS_SERVER_NAME", "env.example.com")
+
+	disableBaseURLCheck := true
+
+	cfg := Config{
+		DisableBaseURLCheck: &disableBaseURLCheck,
+		TLSConfig:           &TLSConfig{ServerName: "config.example.com"},
+	}
+
+	o, _, err := newClientOption([]OptionClientFn{cfg.ToOption()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := deep.Equal(o.TLSConfig, &TLSConfig{MinVersion: "1.2", ServerName: "config.example.com"}); diff != nil {
+		t.Error(diff)
+	}
+
+	if cfg.TLSConfig.MinVersion != "" {
+		t.Error("config changed")
+	}
+}
+
+func TestNewEnvPrecedence(t *testing.T) {
+	t.Setenv("KLIENT_BASE_URL", "http://global")
+	t.Setenv("KLIENT_RETRY_MAX", "7")
+	t.Setenv("KLIENT_PAYMENTS_BASE_URL", "http://payments")
+	t.Setenv("KLIENT_PAYMENTS_RETRY_MAX", "9")
+	t.Setenv("KLIENT_PAYMENTS_TIMEOUT", "3s")
+
+	tests := []struct {
+		name     string
+		opts     []OptionClientFn
+		baseURL  string
+		retryMax int
+		timeout  time.Duration
+	}{
+		{
+			name:     "global",
+			baseURL:  "http://global",
+			retryMax: 7,
+		},
+		{
+			name:     "named",
+			opts:     []OptionClientFn{WithName("payments")},
+			baseURL:  "http://payments",
+			retryMax: 9,
+			timeout:  3 * time.Second,
+		},
+		{
+			name:     "options",
+			opts:     []OptionClientFn{WithName("payments"), WithBaseURL("http://option"), WithRetryMax(2), WithTimeout(time.Second)},
+			baseURL:  "http://option",
+			retryMax: 2,
+			// KLIENT_*_TIMEOUT overrides options
+			timeout: 3 * time.Second,
+		},
+		{
+			name:     "disabled",
+			opts:     []OptionClientFn{WithName("payments"), WithDisableEnvValues(true), WithDisableBaseURLCheck(true)},
+			retryMax: defaultRetryMax,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var baseURL string
+			client, err := New(append(tt.opts, WithBaseTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				baseURL = r.URL.Scheme + "://" + r.URL.Host
+
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+			})))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stream := client.transport.state.Load().stream; stream.RetryMax != tt.retryMax {
+				t.Errorf("retry max %d, want %d", stream.RetryMax, tt.retryMax)
+			}
+
+			if client.HTTP.Timeout != tt.timeout {
+				t.Errorf("timeout %s, want %s", client.HTTP.Timeout, tt.timeout)
+			}
+
+			if tt.baseURL == "" {
+				return
+			}
+
+			req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/test", nil)
+			if err := client.Do(req, func(*http.Response) error { return nil }); err != nil {
+				t.Fatal(err)
+			}
+
+			if baseURL != tt.baseURL {
+				t.Errorf("base url %q, want %q", baseURL, tt.baseURL)
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -1,6 +1,7 @@
 package klient
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -47,3 +48,89 @@ func UnexpectedResponse(resp *http.Response) error {
 
 	return nil
 }
+
+// ResponseErrorOf is a non-2xx response error with the decoded body.
+//
+// If E (or *E) implements error, it can be reached with errors.As.
+type ResponseErrorOf[E any] struct {
+	StatusCode int
+	RequestID  string
+	// Err is the decoded response body.
+	Err E
+	// Problem is the decoded body of application/problem+json responses, nil for others.
+	Problem *ProblemDetails
+	// Body is the limited raw response body.
+	Body string
+}
+
+func (e *ResponseErrorOf[E]) Error() string {
+	msg := e.Body
+	if err := e.Unwrap(); err != nil && err.Error() != "" {
+		msg = err.Error()
+	}
+
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+
+	if e.RequestID == "" {
+		return fmt.Sprintf("unexpected response [%d]: %s", e.StatusCode, msg)
+	}
+
+	return fmt.Sprintf("unexpected response [%d] with request id [%s]: %s", e.StatusCode, e.RequestID, msg)
+}
+
+func (e *ResponseErrorOf[E]) Unwrap() error {
+	if err, ok := any(&e.Err).(error); ok {
+		return err
+	}
+
+	if err, ok := any(e.Err).(error); ok {
+		return err
+	}
+
+	return nil
+}
+
+// ErrResponseOf returns an error with the limited response body decoded into E.
+//
+// If the body cannot be decoded, *ResponseError is returned like ErrResponse.
+func ErrResponseOf[E any](resp *http.Response) error {
+	partialBody, _ := io.ReadAll(io.LimitReader(resp.Body, ResponseErrLimit))
+	requestID := resp.Header.Get("X-Request-Id")
+
+	var v E
+	if err := json.Unmarshal(partialBody, &v); err != nil {
+		return &ResponseError{
+			StatusCode: resp.StatusCode,
+			Body:       string(partialBody),
+			RequestID:  requestID,
+		}
+	}
+
+	respErr := &ResponseErrorOf[E]{
+		StatusCode: resp.StatusCode,
+		RequestID:  requestID,
+		Err:        v,
+		Body:       string(partialBody),
+	}
+
+	if IsProblemJSON(resp) {
+		var problem ProblemDetails
+		if err := json.Unmarshal(partialBody, &problem); err == nil {
+			respErr.Problem = &problem
+		}
+	}
+
+	return respErr
+}
+
+// ErrResponseProblem returns *ResponseErrorOf[ProblemDetails] when the response
+// content type is application/problem+json, otherwise it is same as ErrResponse.
+func ErrResponseProblem(resp *http.Response) error {
+	if IsProblemJSON(resp) {
+		return ErrResponseOf[ProblemDetails](resp)
+	}
+
+	return ErrResponse(resp)
+}
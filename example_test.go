@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/worldline-go/klient"
+	"github.com/worldline-go/klient/klienttest"
 )
 
 type CreateXRequest struct {
@@ -150,15 +151,20 @@ func ExampleWithRetryTimeout() {
 func TestRetryTimeout(t *testing.T) {
 	var attemptCount atomic.Int32
 
+	received := make(chan struct{}, 3)
+
 	// Create a server that delays responses
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		attempt := attemptCount.Add(1)
 		t.Logf("Attempt %d received", attempt)
 
-		// First 2 attempts take too long (will timeout)
+		// First 2 attempts hang until the attempt timeout (will timeout)
 		// Third attempt is fast (will succeed)
 		if attempt < 3 {
-			time.Sleep(3 * time.Second) // Longer than RetryTimeout
+			received <- struct{}{}
+			<-r.Context().Done()
+
+			return
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -166,9 +172,12 @@ func TestRetryTimeout(t *testing.T) {
 	}))
 	defer server.Close()
 
+	clock := klienttest.NewClock(time.Now())
+
 	// Create client with retry timeout
 	client, err := klient.New(
 		klient.WithDisableBaseURLCheck(true),
+		klient.WithClock(clock),
 		klient.WithRetryMax(3),
 		klient.WithRetryWaitMin(100*time.Millisecond),
 		klient.WithRetryWaitMax(200*time.Millisecond),
@@ -178,50 +187,46 @@ func TestRetryTimeout(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	start := time.Now()
-	resp, err := client.HTTP.Do(req)
-	elapsed := time.Since(start)
-
+	// 2s (timeout) + 0.1-0.2s (wait) + 2s (timeout) + 0.1-0.2s (wait) + success
+	resp, err := doWithClock(t, client, req, clock, received, 2, 2, 2*time.Second)
 	if err != nil {
-		t.Fatalf("Request failed after %v: %v", elapsed, err)
+		t.Fatalf("Request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	attempts := attemptCount.Load()
-	t.Logf("Request completed in %v with %d attempts", elapsed, attempts)
+	t.Logf("Request completed with %d attempts", attempts)
 
 	// Should have made 3 attempts (2 timeouts + 1 success)
 	if attempts != 3 {
 		t.Errorf("Expected 3 attempts, got %d", attempts)
 	}
-
-	// Total time should be approximately:
-	// 2s (timeout) + 0.1-0.2s (wait) + 2s (timeout) + 0.1-0.2s (wait) + <1s (success)
-	// = approximately 4-5 seconds
-	if elapsed < 4*time.Second || elapsed > 6*time.Second {
-		t.Logf("Warning: elapsed time %v outside expected range (4-6s)", elapsed)
-	}
 }
 
 func TestRetryTimeoutAllAttemptsTimeout(t *testing.T) {
 	var attemptCount atomic.Int32
 
-	// Create a server that always delays
+	received := make(chan struct{}, 3)
+
+	// Create a server that always hangs until the attempt timeout
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		attempt := attemptCount.Add(1)
 		t.Logf("Attempt %d received", attempt)
-		time.Sleep(5 * time.Second) // Always too slow
-		w.WriteHeader(http.StatusOK)
+		received <- struct{}{}
+		<-r.Context().Done()
 	}))
 	defer server.Close()
 
+	clock := klienttest.NewClock(time.Now())
+
 	client, err := klient.New(
 		klient.WithDisableBaseURLCheck(true),
+		klient.WithClock(clock),
 		klient.WithRetryMax(2), // Will try 3 times total (initial + 2 retries)
 		klient.WithRetryWaitMin(100*time.Millisecond),
 		klient.WithRetryWaitMax(200*time.Millisecond),
@@ -231,21 +236,19 @@ func TestRetryTimeoutAllAttemptsTimeout(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, server.URL, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	start := time.Now()
-	resp, err := client.HTTP.Do(req)
-	elapsed := time.Since(start)
-
+	// 1s (timeout) + 0.1-0.2s (wait) + 1s (timeout) + 0.1-0.2s (wait) + 1s (timeout)
+	resp, err := doWithClock(t, client, req, clock, received, 3, 2, time.Second)
 	if resp != nil {
 		resp.Body.Close()
 	}
 
 	attempts := attemptCount.Load()
-	t.Logf("Request failed after %v with %d attempts (error: %v)", elapsed, attempts, err)
+	t.Logf("Request failed with %d attempts (error: %v)", attempts, err)
 
 	// Should have made 3 attempts (all timeouts)
 	if attempts != 3 {
@@ -256,11 +259,48 @@ func TestRetryTimeoutAllAttemptsTimeout(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error due to timeouts, got nil")
 	}
+}
+
+// doWithClock sends the request, the first hangs attempts time out on the clock after they are received,
+// followed by the retry wait for the first waits of them.
+func doWithClock(t *testing.T, client *klient.Client, req *http.Request, clock *klienttest.Clock, received <-chan struct{}, hangs, waits int, timeout time.Duration) (*http.Response, error) {
+	t.Helper()
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
 
-	// Total time should be approximately:
-	// 1s (timeout) + 0.1-0.2s (wait) + 1s (timeout) + 0.1-0.2s (wait) + 1s (timeout)
-	// = approximately 3-4 seconds
-	if elapsed < 3*time.Second || elapsed > 4*time.Second {
-		t.Logf("Warning: elapsed time %v outside expected range (3-4s)", elapsed)
+	resultCh := make(chan result, 1)
+	go func() {
+		resp, err := client.HTTP.Do(req)
+		resultCh <- result{resp: resp, err: err}
+	}()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	for i := range hangs {
+		select {
+		case <-received:
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+
+		// attempt timeout
+		clock.Advance(timeout)
+
+		if i < waits {
+			if err := clock.BlockUntil(ctx, 1); err != nil {
+				t.Fatal(err)
+			}
+
+			// retry wait, shorter than the timeout
+			clock.Advance(timeout)
+		}
 	}
+
+	r := <-resultCh
+
+	return r.resp, r.err
 }
@@ -3,16 +3,21 @@ module github.com/worldline-go/klient
 go 1.24.0
 
 require (
+	github.com/andybalholm/brotli v1.2.0
 	github.com/go-test/deep v1.1.1
 	github.com/hashicorp/go-cleanhttp v0.5.2
 	github.com/hashicorp/go-retryablehttp v0.7.8
+	github.com/klauspost/compress v1.18.0
 	github.com/rs/zerolog v1.34.0
-	github.com/twmb/tlscfg v1.2.1
 	github.com/worldline-go/logz v0.5.5
+	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78
+	gopkg.in/yaml.v3 v3.0.1
+	software.sslmate.com/src/go-pkcs12 v0.7.3
 )
 
 require (
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
+	golang.org/x/crypto v0.38.0 // indirect
 	golang.org/x/sys v0.37.0 // indirect
 )
@@ -0,0 +1,423 @@
+package klienttest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInteractionNotFound = errors.New("klienttest: interaction not found in cassette")
+
+// RecorderMode is the mode of the cassette Recorder.
+type RecorderMode int
+
+const (
+	// ModeReplay replays recorded interactions, unmatched requests fail with ErrInteractionNotFound.
+	ModeReplay RecorderMode = iota
+	// ModeRecord sends all requests to the base transport and records them.
+	ModeRecord
+	// ModeRecordMissing replays recorded interactions and records the unmatched ones.
+	ModeRecordMissing
+)
+
+// Redacted is the replacement value of redacted headers.
+const Redacted = "REDACTED"
+
+// Cassette is the stored interactions.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// Interaction is a recorded request and response.
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"  yaml:"request"`
+	Response CassetteResponse `json:"response" yaml:"response"`
+}
+
+type CassetteRequest struct {
+	Method       string      `json:"method"           yaml:"method"`
+	URL          string      `json:"url"              yaml:"url"`
+	Header       http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	CassetteBody `yaml:",inline"`
+}
+
+type CassetteResponse struct {
+	StatusCode   int         `json:"status_code"      yaml:"status_code"`
+	Header       http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	CassetteBody `yaml:",inline"`
+}
+
+// CassetteBody stores text bodies as is and binary bodies base64 encoded.
+type CassetteBody struct {
+	Body       string `json:"body,omitempty"        yaml:"body,omitempty"`
+	BodyBase64 string `json:"body_base64,omitempty" yaml:"body_base64,omitempty"`
+}
+
+func newCassetteBody(data []byte) CassetteBody {
+	if utf8.Valid(data) {
+		return CassetteBody{Body: string(data)}
+	}
+
+	return CassetteBody{BodyBase64: base64.StdEncoding.EncodeToString(data)}
+}
+
+// Bytes returns the decoded body.
+func (b CassetteBody) Bytes() []byte {
+	if b.BodyBase64 != "" {
+		v, _ := base64.StdEncoding.DecodeString(b.BodyBase64)
+
+		return v
+	}
+
+	return []byte(b.Body)
+}
+
+// Matcher reports whether the request with body matches the recorded request.
+type Matcher func(req *http.Request, body []byte, recorded CassetteRequest) bool
+
+// MatchMethod matches the request method.
+func MatchMethod(req *http.Request, _ []byte, recorded CassetteRequest) bool {
+	return req.Method == recorded.Method
+}
+
+// MatchURL matches the full request URL.
+func MatchURL(req *http.Request, _ []byte, recorded CassetteRequest) bool {
+	return req.URL.String() == recorded.URL
+}
+
+// MatchPath matches the request URL path without host and query.
+func MatchPath(req *http.Request, _ []byte, recorded CassetteRequest) bool {
+	u, err := req.URL.Parse(recorded.URL)
+
+	return err == nil && u.Path == req.URL.Path
+}
+
+// MatchBody matches the request body.
+func MatchBody(_ *http.Request, body []byte, recorded CassetteRequest) bool {
+	return bytes.Equal(body, recorded.Bytes())
+}
+
+// MatchHeader returns a matcher of the header values.
+func MatchHeader(keys ...string) Matcher {
+	return func(req *http.Request, _ []byte, recorded CassetteRequest) bool {
+		for _, key := range keys {
+			if !slices.Equal(req.Header.Values(key), recorded.Header.Values(key)) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+type optionRecorderValue struct {
+	Matchers      []Matcher
+	RedactHeaders []string
+	Redact        []func(*Interaction)
+	Base          http.RoundTripper
+}
+
+type OptionRecorderFn func(*optionRecorderValue)
+
+// WithMatchers sets the request matchers, default is MatchMethod, MatchURL and MatchBody.
+func WithMatchers(matchers ...Matcher) OptionRecorderFn {
+	return func(o *optionRecorderValue) {
+		o.Matchers = matchers
+	}
+}
+
+// WithRedactHeaders replaces values of the request and response headers with Redacted before storing.
+//
+// Authorization, Cookie and Set-Cookie headers are always redacted.
+func WithRedactHeaders(keys ...string) OptionRecorderFn {
+	return func(o *optionRecorderValue) {
+		o.RedactHeaders = append(o.RedactHeaders, keys...)
+	}
+}
+
+// WithRedact adds a function to modify the interaction before storing, e.g. to remove secrets in bodies.
+func WithRedact(fn func(*Interaction)) OptionRecorderFn {
+	return func(o *optionRecorderValue) {
+		o.Redact = append(o.Redact, fn)
+	}
+}
+
+// WithRecorderBase sets the transport used for recording, default is http.DefaultTransport.
+func WithRecorderBase(base http.RoundTripper) OptionRecorderFn {
+	return func(o *optionRecorderValue) {
+		o.Base = base
+	}
+}
+
+// Recorder is a record/replay http.RoundTripper, use with klient.WithBaseTransport.
+//
+// Cassette file format is YAML for .yaml and .yml extensions, otherwise JSON.
+// Responses are recorded with "Accept-Encoding: identity" to store the bodies as plain text.
+//
+//	rec, err := klienttest.NewRecorder("testdata/partner.yaml", klienttest.ModeRecordMissing)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	t.Cleanup(func() { _ = rec.Save() })
+//
+//	client, err := klient.New(klient.WithBaseTransport(rec))
+type Recorder struct {
+	path string
+	mode RecorderMode
+	o    optionRecorderValue
+
+	m        sync.Mutex
+	cassette Cassette
+	used     []bool
+	changed  bool
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// NewRecorder loads the cassette in path and returns a new Recorder.
+//
+// Missing cassette is an error only in ModeReplay.
+func NewRecorder(path string, mode RecorderMode, opts ...OptionRecorderFn) (*Recorder, error) {
+	o := optionRecorderValue{
+		Matchers:      []Matcher{MatchMethod, MatchURL, MatchBody},
+		RedactHeaders: []string{"Authorization", "Cookie", "Set-Cookie"},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.Base == nil {
+		o.Base = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		path: path,
+		mode: mode,
+		o:    o,
+	}
+
+	if mode != ModeRecord {
+		if err := r.load(); err != nil {
+			if mode == ModeReplay || !errors.Is(err, os.ErrNotExist) {
+				return nil, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Recorder) load() error {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return fmt.Errorf("klienttest: read cassette: %w", err)
+	}
+
+	if isYAML(r.path) {
+		err = yaml.Unmarshal(data, &r.cassette)
+	} else {
+		err = json.Unmarshal(data, &r.cassette)
+	}
+
+	if err != nil {
+		return fmt.Errorf("klienttest: decode cassette: %w", err)
+	}
+
+	r.used = make([]bool, len(r.cassette.Interactions))
+
+	return nil
+}
+
+// Save writes the cassette file when there are recorded interactions.
+func (r *Recorder) Save() error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	if !r.changed {
+		return nil
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+
+	if isYAML(r.path) {
+		data, err = yaml.Marshal(r.cassette)
+	} else {
+		data, err = json.MarshalIndent(r.cassette, "", "  ")
+	}
+
+	if err != nil {
+		return fmt.Errorf("klienttest: encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("klienttest: create cassette directory: %w", err)
+	}
+
+	if err := os.WriteFile(r.path, data, 0o644); err != nil {
+		return fmt.Errorf("klienttest: write cassette: %w", err)
+	}
+
+	r.changed = false
+
+	return nil
+}
+
+// Interactions returns the interactions of the cassette.
+func (r *Recorder) Interactions() []*Interaction {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	return append([]*Interaction(nil), r.cassette.Interactions...)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.mode != ModeRecord {
+		if interaction := r.find(req, body); interaction != nil {
+			return interaction.Response.toHTTP(req), nil
+		}
+
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, req.URL)
+		}
+	}
+
+	return r.record(req, body)
+}
+
+// find returns the first not used matching interaction, or the last matching one when all used.
+func (r *Recorder) find(req *http.Request, body []byte) *Interaction {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	var found *Interaction
+	for i, interaction := range r.cassette.Interactions {
+		if !r.match(req, body, interaction.Request) {
+			continue
+		}
+
+		if !r.used[i] {
+			r.used[i] = true
+
+			return interaction
+		}
+
+		found = interaction
+	}
+
+	return found
+}
+
+func (r *Recorder) match(req *http.Request, body []byte, recorded CassetteRequest) bool {
+	for _, matcher := range r.o.Matchers {
+		if !matcher(req, body, recorded) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	req2 := req.Clone(req.Context())
+	req2.Body = io.NopCloser(bytes.NewReader(body))
+	req2.ContentLength = int64(len(body))
+	// plain response body to read and redact in the cassette
+	req2.Header.Set("Accept-Encoding", "identity")
+
+	resp, err := r.o.Base.RoundTrip(req2)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: CassetteRequest{
+			Method:       req.Method,
+			URL:          req.URL.String(),
+			Header:       req.Header.Clone(),
+			CassetteBody: newCassetteBody(body),
+		},
+		Response: CassetteResponse{
+			StatusCode:   resp.StatusCode,
+			Header:       resp.Header.Clone(),
+			CassetteBody: newCassetteBody(respBody),
+		},
+	}
+
+	r.redact(interaction)
+
+	r.m.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.used = append(r.used, true)
+	r.changed = true
+	r.m.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	return resp, nil
+}
+
+func (r *Recorder) redact(interaction *Interaction) {
+	for _, key := range r.o.RedactHeaders {
+		for _, header := range []http.Header{interaction.Request.Header, interaction.Response.Header} {
+			if values := header.Values(key); len(values) > 0 {
+				header.Del(key)
+
+				for range values {
+					header.Add(key, Redacted)
+				}
+			}
+		}
+	}
+
+	for _, fn := range r.o.Redact {
+		fn(interaction)
+	}
+}
+
+func (r CassetteResponse) toHTTP(req *http.Request) *http.Response {
+	return Response{
+		StatusCode: r.StatusCode,
+		Header:     r.Header.Clone(),
+		Body:       r.Bytes(),
+	}.toHTTP(req)
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	return ext == ".yaml" || ext == ".yml"
+}
@@ -0,0 +1,150 @@
+package klienttest
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/worldline-go/klient"
+)
+
+func TestRecorder(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello " + r.URL.Query().Get("name")))
+	}))
+
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+
+	get := func(t *testing.T, client *klient.Client, name string) (string, error) {
+		t.Helper()
+
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/greet?name="+name, nil)
+		req.Header.Set("Authorization", "Bearer secret")
+
+		var body string
+		err := client.Do(req, func(resp *http.Response) error {
+			v, err := io.ReadAll(resp.Body)
+			body = string(v)
+
+			return err
+		})
+
+		return body, err
+	}
+
+	newClient := func(t *testing.T, rec *Recorder) *klient.Client {
+		t.Helper()
+
+		client, err := klient.NewPlain(klient.WithBaseURL(httpServer.URL), klient.WithBaseTransport(rec))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return client
+	}
+
+	// record
+	rec, err := NewRecorder(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body, err := get(t, newClient(t, rec), "a"); err != nil || body != "hello a" {
+		t.Fatalf("unexpected response %q, %v", body, err)
+	}
+
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(data), "secret") || !strings.Contains(string(data), Redacted) {
+		t.Fatalf("expected redacted cassette, got %s", data)
+	}
+
+	// replay without server
+	baseURL := httpServer.URL
+	httpServer.Close()
+
+	rec, err = NewRecorder(path, ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := klient.NewPlain(klient.WithBaseURL(baseURL), klient.WithBaseTransport(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body, err := get(t, client, "a"); err != nil || body != "hello a" {
+		t.Fatalf("unexpected replay response %q, %v", body, err)
+	}
+
+	if _, err := get(t, client, "b"); !errors.Is(err, ErrInteractionNotFound) {
+		t.Fatalf("expected ErrInteractionNotFound, got %v", err)
+	}
+}
+
+func TestRecorderCompression(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			_, _ = w.Write([]byte(`{"name":"plain"}`))
+
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = gz.Write([]byte(`{"name":"compressed"}`))
+		_ = gz.Close()
+	}))
+	defer httpServer.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.yaml")
+
+	rec, err := NewRecorder(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := klient.NewPlain(klient.WithBaseURL(httpServer.URL), klient.WithBaseTransport(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/user", nil)
+
+	var got map[string]string
+	if err := client.Do(req, klient.ResponseFuncJSON(&got)); err != nil {
+		t.Fatal(err)
+	}
+
+	if got["name"] != "plain" {
+		t.Errorf("unexpected response %v", got)
+	}
+
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), `{"name":"plain"}`) || strings.Contains(string(data), "body_base64") {
+		t.Errorf("expected plain text body in the cassette\n%s", data)
+	}
+}
@@ -0,0 +1,157 @@
+package klienttest
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/worldline-go/klient"
+)
+
+// Clock is a controllable klient.Clock, time only moves with Advance.
+//
+// Use BlockUntil to wait the client to start waiting before advancing the time.
+//
+//	clock := klienttest.NewClock(time.Now())
+//	client, _ := klient.New(klient.WithClock(clock), klient.WithBaseTransport(mock))
+//
+//	go func() { errCh <- client.Do(req, fn) }()
+//
+//	_ = clock.BlockUntil(ctx, 1) // first retry wait
+//	clock.Advance(time.Second)
+type Clock struct {
+	m       sync.Mutex
+	now     time.Time
+	timers  []*clockTimer
+	waits   []time.Duration
+	changed chan struct{}
+}
+
+var _ klient.Clock = (*Clock)(nil)
+
+// NewClock returns a new clock starting at now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{
+		now:     now,
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.now
+}
+
+// NewTimer returns a timer firing when the clock is advanced by d.
+func (c *Clock) NewTimer(d time.Duration) klient.Timer {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	t := &clockTimer{
+		clock: c,
+		when:  c.now.Add(d),
+		c:     make(chan time.Time, 1),
+	}
+
+	c.waits = append(c.waits, d)
+
+	if d <= 0 {
+		t.c <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+
+	c.notify()
+
+	return t
+}
+
+// Advance moves the time forward by d and fires the expired timers.
+func (c *Clock) Advance(d time.Duration) {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	c.now = c.now.Add(d)
+
+	c.timers = slices.DeleteFunc(c.timers, func(t *clockTimer) bool {
+		if t.when.After(c.now) {
+			return false
+		}
+
+		t.c <- c.now
+
+		return true
+	})
+
+	c.notify()
+}
+
+// Pending returns the number of not fired and not stopped timers.
+func (c *Clock) Pending() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return len(c.timers)
+}
+
+// Waits returns the durations of all created timers in order, useful to check backoff schedules.
+func (c *Clock) Waits() []time.Duration {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return slices.Clone(c.waits)
+}
+
+// BlockUntil waits until there are n pending timers.
+func (c *Clock) BlockUntil(ctx context.Context, n int) error {
+	for {
+		c.m.Lock()
+		pending, changed := len(c.timers), c.changed
+		c.m.Unlock()
+
+		if pending >= n {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// notify wakes up BlockUntil, caller should hold the lock.
+func (c *Clock) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+type clockTimer struct {
+	clock *Clock
+	when  time.Time
+	c     chan time.Time
+}
+
+func (t *clockTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *clockTimer) Stop() bool {
+	t.clock.m.Lock()
+	defer t.clock.m.Unlock()
+
+	index := slices.Index(t.clock.timers, t)
+	if index < 0 {
+		return false
+	}
+
+	t.clock.timers = slices.Delete(t.clock.timers, index, index+1)
+	t.clock.notify()
+
+	return true
+}
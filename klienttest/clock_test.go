@@ -0,0 +1,190 @@
+package klienttest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/worldline-go/klient"
+)
+
+func TestClockBackoff(t *testing.T) {
+	mock := NewMock(t)
+	mock.On(http.MethodGet, "/retry").
+		Reply(http.StatusServiceUnavailable, nil, nil).
+		Reply(http.StatusServiceUnavailable, nil, nil).
+		Reply(http.StatusServiceUnavailable, nil, nil).
+		Reply(http.StatusOK, nil, nil).
+		Times(4)
+
+	clock := NewClock(time.Now())
+
+	client, err := klient.New(
+		klient.WithBaseURL("http://example.com"),
+		klient.WithBaseTransport(mock),
+		klient.WithClock(clock),
+		klient.WithRetryWaitMin(time.Second),
+		klient.WithRetryWaitMax(time.Minute),
+		klient.WithRetryMax(3),
+		klient.WithRetryLog(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/retry", nil)
+		errCh <- client.Do(req, func(*http.Response) error { return nil })
+	}()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	for range 3 {
+		if err := clock.BlockUntil(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		clock.Advance(time.Hour)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}; !slices.Equal(clock.Waits(), want) {
+		t.Errorf("unexpected backoff schedule %v, want %v", clock.Waits(), want)
+	}
+}
+
+func TestClockBackoffCancel(t *testing.T) {
+	mock := NewMock(t)
+
+	fault := NewFault(mock)
+	fault.Route(http.MethodGet, "/down").Always(Fault{Err: ErrConnRefused})
+
+	clock := NewClock(time.Now())
+
+	client, err := klient.New(
+		klient.WithBaseURL("http://example.com"),
+		klient.WithBaseTransport(fault),
+		klient.WithClock(clock),
+		klient.WithRetryWaitMin(time.Minute),
+		klient.WithRetryWaitMax(time.Minute),
+		klient.WithRetryLog(false),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqCtx, reqCancel := context.WithCancel(t.Context())
+	defer reqCancel()
+
+	errCh := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, "/down", nil)
+		errCh <- client.Do(req, func(*http.Response) error { return nil })
+	}()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	// connection error has no response, wait is still canceled with the request
+	if err := clock.BlockUntil(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	reqCancel()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context canceled, got %v", err)
+		}
+	case <-ctx.Done():
+		t.Fatal("retry wait not canceled")
+	}
+}
+
+func TestClockRetryTimeout(t *testing.T) {
+	mock := NewMock(t)
+	mock.On(http.MethodGet, "/slow").Reply(http.StatusOK, nil, nil).Once()
+
+	fault := NewFault(mock)
+	fault.Route(http.MethodGet, "/slow").Sequence(Fault{Hang: true})
+
+	clock := NewClock(time.Now())
+
+	client, err := klient.New(
+		klient.WithBaseURL("http://example.com"),
+		klient.WithBaseTransport(fault),
+		klient.WithClock(clock),
+		klient.WithRetryWaitMin(time.Second),
+		klient.WithRetryWaitMax(time.Second),
+		klient.WithRetryTimeout(10*time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/slow", nil)
+		errCh <- client.Do(req, func(*http.Response) error { return nil })
+	}()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	// attempt timeout, then retry wait
+	for _, d := range []time.Duration{10 * time.Second, time.Second} {
+		if err := clock.BlockUntil(ctx, 1); err != nil {
+			t.Fatal(err)
+		}
+
+		clock.Advance(d)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []time.Duration{10 * time.Second, time.Second, 10 * time.Second}; !slices.Equal(clock.Waits(), want) {
+		t.Errorf("unexpected timers %v, want %v", clock.Waits(), want)
+	}
+}
+
+func TestClockTimer(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(start)
+
+	t1 := clock.NewTimer(time.Second)
+	t2 := clock.NewTimer(2 * time.Second)
+
+	clock.Advance(time.Second)
+
+	select {
+	case now := <-t1.C():
+		if !now.Equal(start.Add(time.Second)) {
+			t.Errorf("unexpected fire time %s", now)
+		}
+	default:
+		t.Fatal("timer not fired")
+	}
+
+	if !t2.Stop() {
+		t.Error("expected stop of pending timer")
+	}
+
+	if t1.Stop() {
+		t.Error("expected no stop of fired timer")
+	}
+
+	if clock.Pending() != 0 {
+		t.Errorf("unexpected pending timers %d", clock.Pending())
+	}
+}
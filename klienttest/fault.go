@@ -0,0 +1,338 @@
+package klienttest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/worldline-go/klient"
+)
+
+var (
+	// ErrConnRefused is a dial error like a closed port.
+	ErrConnRefused error = &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	// ErrConnReset is a read error like a dropped connection.
+	ErrConnReset error = &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+)
+
+// Latency returns a delay with the random source.
+type Latency func(r *rand.Rand) time.Duration
+
+// LatencyFixed returns always d.
+func LatencyFixed(d time.Duration) Latency {
+	return func(*rand.Rand) time.Duration {
+		return d
+	}
+}
+
+// LatencyUniform returns a uniformly distributed delay in [low, high).
+func LatencyUniform(low, high time.Duration) Latency {
+	return func(r *rand.Rand) time.Duration {
+		if high <= low {
+			return low
+		}
+
+		return low + time.Duration(r.Int64N(int64(high-low)))
+	}
+}
+
+// LatencyNormal returns a normally distributed delay, negative values are returned as 0.
+func LatencyNormal(mean, stddev time.Duration) Latency {
+	return func(r *rand.Rand) time.Duration {
+		return max(0, mean+time.Duration(r.NormFloat64()*float64(stddev)))
+	}
+}
+
+// Fault is a failure injected to a request, zero value passes the request to the base transport.
+type Fault struct {
+	// Latency delays the request before sending.
+	Latency Latency
+	// Err returns as the transport error, see ErrConnRefused and ErrConnReset.
+	Err error
+	// Hang blocks until the request context is done, like an unresponsive server.
+	Hang bool
+	// StatusCode returns the response without calling the base transport.
+	StatusCode int
+	// Header and Body of the StatusCode response.
+	Header http.Header
+	Body   []byte
+	// TruncateBody cuts the response body after TruncateAfter bytes with io.ErrUnexpectedEOF.
+	TruncateBody  bool
+	TruncateAfter int64
+	// SlowBody delays each read of the response body, reads return at most SlowBodyChunk bytes.
+	SlowBody      time.Duration
+	SlowBodyChunk int
+}
+
+// FaultTransport is an http.RoundTripper injecting faults to routes, use with klient.WithBaseTransport.
+//
+// Routes are checked in registration order, first matching route decides the fault.
+// Requests without matching route or fault are sent to the base transport.
+//
+//	fault := klienttest.NewFault(mock).Seed(1)
+//	fault.Route(http.MethodGet, "/users/{id}").
+//		Sequence(klienttest.Fault{Err: klienttest.ErrConnRefused}, klienttest.Fault{StatusCode: 503}).
+//		Probability(0.1, klienttest.Fault{Latency: klienttest.LatencyUniform(0, time.Second)})
+//
+//	client, _ := klient.New(klient.WithBaseURL("http://api"), klient.WithBaseTransport(fault))
+type FaultTransport struct {
+	base http.RoundTripper
+
+	m      sync.Mutex
+	rand   *rand.Rand
+	clock  klient.Clock
+	routes []*FaultRoute
+}
+
+var _ http.RoundTripper = (*FaultTransport)(nil)
+
+// NewFault returns a new fault transport, nil base is http.DefaultTransport.
+func NewFault(base http.RoundTripper) *FaultTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &FaultTransport{
+		base: base,
+		rand: rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())), //nolint:gosec // not for security
+	}
+}
+
+// Seed sets the seed of the random source to make probabilities and latencies deterministic.
+func (f *FaultTransport) Seed(seed uint64) *FaultTransport {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.rand = rand.New(rand.NewPCG(seed, seed)) //nolint:gosec // not for security
+
+	return f
+}
+
+// Clock sets the clock of latencies and slow body reads, default is the system clock.
+func (f *FaultTransport) Clock(clock klient.Clock) *FaultTransport {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	f.clock = clock
+
+	return f
+}
+
+// Route registers a new route with method and path pattern, see Mock.On for the pattern.
+func (f *FaultTransport) Route(method, pathPattern string) *FaultRoute {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	r := &FaultRoute{
+		method:  method,
+		pattern: pathPattern,
+	}
+
+	f.routes = append(f.routes, r)
+
+	return r
+}
+
+func (f *FaultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fault, latency, clock := f.fault(req)
+
+	if err := sleep(req.Context(), clock, latency); err != nil {
+		return nil, err
+	}
+
+	if fault.Hang {
+		<-req.Context().Done()
+
+		return nil, req.Context().Err()
+	}
+
+	if fault.Err != nil {
+		return nil, fault.Err
+	}
+
+	var resp *http.Response
+	if fault.StatusCode != 0 {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+
+		resp = Response{StatusCode: fault.StatusCode, Header: fault.Header.Clone(), Body: fault.Body}.toHTTP(req)
+	} else {
+		var err error
+		resp, err = f.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if fault.TruncateBody {
+		resp.Body = &truncateBody{ReadCloser: resp.Body, remain: fault.TruncateAfter}
+	}
+
+	if fault.SlowBody > 0 {
+		chunk := fault.SlowBodyChunk
+		if chunk <= 0 {
+			chunk = 1
+		}
+
+		resp.Body = &slowBody{ReadCloser: resp.Body, ctx: req.Context(), clock: clock, delay: fault.SlowBody, chunk: chunk}
+	}
+
+	return resp, nil
+}
+
+// fault returns the fault of the request with its latency and the clock.
+func (f *FaultTransport) fault(req *http.Request) (Fault, time.Duration, klient.Clock) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	for _, route := range f.routes {
+		if route.method != "" && !strings.EqualFold(route.method, req.Method) {
+			continue
+		}
+
+		if _, ok := matchPath(route.pattern, req.URL.Path); !ok {
+			continue
+		}
+
+		fault := route.next(f.rand)
+		if fault.Latency == nil {
+			return fault, 0, f.clock
+		}
+
+		return fault, fault.Latency(f.rand), f.clock
+	}
+
+	return Fault{}, 0, f.clock
+}
+
+// FaultRoute is a registered route with faults.
+type FaultRoute struct {
+	method  string
+	pattern string
+
+	sequence      []Fault
+	probabilities []faultProbability
+	calls         int
+}
+
+type faultProbability struct {
+	p     float64
+	fault Fault
+}
+
+// Sequence queues faults for the next calls in order, use zero Fault to pass a call.
+//
+// Probabilities are used after the sequence is consumed.
+func (r *FaultRoute) Sequence(faults ...Fault) *FaultRoute {
+	r.sequence = append(r.sequence, faults...)
+
+	return r
+}
+
+// Probability injects the fault with probability p in [0, 1].
+//
+// Probabilities are checked in registration order, first hit is injected.
+func (r *FaultRoute) Probability(p float64, fault Fault) *FaultRoute {
+	r.probabilities = append(r.probabilities, faultProbability{p: p, fault: fault})
+
+	return r
+}
+
+// Always injects the fault to all calls after the sequence.
+func (r *FaultRoute) Always(fault Fault) *FaultRoute {
+	return r.Probability(1, fault)
+}
+
+// next returns the fault of the next call, caller should hold the transport lock.
+func (r *FaultRoute) next(rnd *rand.Rand) Fault {
+	call := r.calls
+	r.calls++
+
+	if call < len(r.sequence) {
+		return r.sequence[call]
+	}
+
+	for _, p := range r.probabilities {
+		if p.p >= 1 || rnd.Float64() < p.p {
+			return p.fault
+		}
+	}
+
+	return Fault{}
+}
+
+// sleep waits d on the clock, nil clock is the system clock.
+func sleep(ctx context.Context, clock klient.Clock, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	var c <-chan time.Time
+	if clock != nil {
+		timer := clock.NewTimer(d)
+		defer timer.Stop()
+
+		c = timer.C()
+	} else {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		c = timer.C
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c:
+		return nil
+	}
+}
+
+type truncateBody struct {
+	io.ReadCloser
+	remain int64
+}
+
+func (b *truncateBody) Read(p []byte) (int, error) {
+	if b.remain <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if int64(len(p)) > b.remain {
+		p = p[:b.remain]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remain -= int64(n)
+
+	return n, err
+}
+
+type slowBody struct {
+	io.ReadCloser
+	ctx   context.Context
+	clock klient.Clock
+	delay time.Duration
+	chunk int
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	if err := sleep(b.ctx, b.clock, b.delay); err != nil {
+		return 0, fmt.Errorf("slow body: %w", err)
+	}
+
+	if len(p) > b.chunk {
+		p = p[:b.chunk]
+	}
+
+	return b.ReadCloser.Read(p)
+}
@@ -0,0 +1,195 @@
+package klienttest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/worldline-go/klient"
+)
+
+func TestFaultSequence(t *testing.T) {
+	mock := NewMock(t)
+	mock.On(http.MethodGet, "/users/{id}").Reply(http.StatusOK, []byte(`ok`), nil).Once()
+
+	fault := NewFault(mock)
+	fault.Route(http.MethodGet, "/users/{id}").Sequence(
+		Fault{Err: ErrConnRefused},
+		Fault{StatusCode: http.StatusServiceUnavailable},
+		Fault{Latency: LatencyFixed(10 * time.Millisecond)},
+	)
+
+	client, err := klient.New(
+		klient.WithBaseURL("http://example.com"),
+		klient.WithBaseTransport(fault),
+		klient.WithRetryWaitMin(0),
+		klient.WithRetryWaitMax(0),
+		klient.WithRetryMax(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/users/1", nil)
+
+	start := time.Now()
+	if err := client.Do(req, func(resp *http.Response) error {
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "ok" {
+			t.Errorf("unexpected body %q", body)
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("latency not injected, elapsed %s", elapsed)
+	}
+}
+
+func TestFaultRetryMax(t *testing.T) {
+	fault := NewFault(nil)
+	fault.Route("", "/{path...}").Always(Fault{StatusCode: http.StatusBadGateway})
+
+	client, err := klient.New(
+		klient.WithBaseURL("http://example.com"),
+		klient.WithBaseTransport(fault),
+		klient.WithRetryWaitMin(0),
+		klient.WithRetryWaitMax(0),
+		klient.WithRetryMax(3),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/any/path", nil)
+	if err := client.Do(req, func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusBadGateway {
+			t.Errorf("unexpected status %d", resp.StatusCode)
+		}
+
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls := fault.routes[0].calls; calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+}
+
+func TestFaultRetryTimeout(t *testing.T) {
+	mock := NewMock(t)
+	mock.On(http.MethodGet, "/slow").Reply(http.StatusOK, nil, nil).Once()
+
+	fault := NewFault(mock)
+	fault.Route(http.MethodGet, "/slow").Sequence(Fault{Hang: true})
+
+	client, err := klient.New(
+		klient.WithBaseURL("http://example.com"),
+		klient.WithBaseTransport(fault),
+		klient.WithRetryWaitMin(0),
+		klient.WithRetryWaitMax(0),
+		klient.WithRetryTimeout(20*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/slow", nil)
+	if err := client.Do(req, func(*http.Response) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFaultBody(t *testing.T) {
+	fault := NewFault(nil)
+	fault.Route(http.MethodGet, "/truncate").Always(Fault{
+		StatusCode:    http.StatusOK,
+		Body:          []byte("0123456789"),
+		TruncateBody:  true,
+		TruncateAfter: 4,
+	})
+	fault.Route(http.MethodGet, "/slow").Always(Fault{
+		StatusCode:    http.StatusOK,
+		Body:          []byte("0123456789"),
+		SlowBody:      time.Millisecond,
+		SlowBodyChunk: 2,
+	})
+
+	client := &http.Client{Transport: fault}
+
+	resp, err := client.Get("http://example.com/truncate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) || string(body) != "0123" {
+		t.Errorf("unexpected truncate result %q, %v", body, err)
+	}
+
+	resp, err = client.Get("http://example.com/slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	body, err = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	if err != nil || string(body) != "0123456789" {
+		t.Errorf("unexpected slow body result %q, %v", body, err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 5*time.Millisecond {
+		t.Errorf("slow body not delayed, elapsed %s", elapsed)
+	}
+}
+
+func TestFaultProbabilitySeed(t *testing.T) {
+	run := func() []int {
+		fault := NewFault(nil).Seed(42)
+		fault.Route("", "/").
+			Probability(0.3, Fault{StatusCode: http.StatusInternalServerError}).
+			Always(Fault{StatusCode: http.StatusOK})
+
+		client := &http.Client{Transport: fault}
+
+		var codes []int
+		for range 20 {
+			resp, err := client.Get("http://example.com/")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_ = resp.Body.Close()
+			codes = append(codes, resp.StatusCode)
+		}
+
+		return codes
+	}
+
+	first, second := run(), run()
+
+	failed := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("same seed returned different results %v, %v", first, second)
+		}
+
+		if first[i] == http.StatusInternalServerError {
+			failed++
+		}
+	}
+
+	if failed == 0 || failed == len(first) {
+		t.Errorf("unexpected distribution %v", first)
+	}
+}
@@ -0,0 +1,439 @@
+package klienttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Mock is an http.RoundTripper with expectations, use with klient.WithBaseTransport.
+//
+// Expectations are checked in registration order, first matching expectation
+// which not exhausted its call count returns the response.
+// Verify is called on test cleanup to check all expectations are met and
+// no unexpected requests received.
+//
+//	mock := klienttest.NewMock(t)
+//	mock.On(http.MethodGet, "/users/{id}").
+//		WithQuery("expand", "true").
+//		ReplyJSON(http.StatusOK, map[string]any{"id": 1}).
+//		Once()
+//
+//	client, _ := klient.New(klient.WithBaseURL("http://api"), klient.WithBaseTransport(mock))
+type Mock struct {
+	t testing.TB
+
+	m            sync.Mutex
+	expectations []*Expectation
+	requests     []*Request
+	unexpected   []*Request
+}
+
+var _ http.RoundTripper = (*Mock)(nil)
+
+// Request is a recorded request.
+type Request struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+	Body   []byte
+	// PathValues are the values of {name} segments of the matched path pattern.
+	PathValues map[string]string
+}
+
+// JSON decodes the request body to v.
+func (r *Request) JSON(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Response is the mock response.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Err returns as the transport error when set.
+	Err error
+}
+
+// NewMock returns a new mock transport and registers Verify to t.Cleanup.
+func NewMock(t testing.TB) *Mock {
+	t.Helper()
+
+	m := &Mock{t: t}
+	t.Cleanup(m.Verify)
+
+	return m
+}
+
+// On registers a new expectation with method and path pattern.
+//
+// Empty method matches all methods. Path pattern segments could be {name} to
+// match a single segment or {name...} to match rest of the path.
+func (m *Mock) On(method, pathPattern string) *Expectation {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	e := &Expectation{
+		method:  method,
+		pattern: pathPattern,
+		times:   -1,
+	}
+
+	m.expectations = append(m.expectations, e)
+
+	return e
+}
+
+// Requests returns all received requests.
+func (m *Mock) Requests() []*Request {
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	return append([]*Request(nil), m.requests...)
+}
+
+// Verify reports the unmet expectations and unexpected requests to the test.
+func (m *Mock) Verify() {
+	m.t.Helper()
+
+	m.m.Lock()
+	defer m.m.Unlock()
+
+	for _, e := range m.expectations {
+		e.m.Lock()
+		calls, times := e.calls, e.times
+		e.m.Unlock()
+
+		switch {
+		case times < 0 && calls == 0:
+			m.t.Errorf("klienttest: expected call of %s, not called", e)
+		case times >= 0 && calls != times:
+			m.t.Errorf("klienttest: expected %d calls of %s, got %d", times, e, calls)
+		}
+	}
+
+	for _, r := range m.unexpected {
+		m.t.Errorf("klienttest: unexpected request %s %s", r.Method, r.URL)
+	}
+}
+
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recorded := &Request{
+		Method: req.Method,
+		URL:    req.URL,
+		Header: req.Header.Clone(),
+		Body:   body,
+	}
+
+	m.m.Lock()
+	m.requests = append(m.requests, recorded)
+	expectations := append([]*Expectation(nil), m.expectations...)
+	m.m.Unlock()
+
+	for _, e := range expectations {
+		pathValues, ok := e.match(recorded)
+		if !ok {
+			continue
+		}
+
+		recorded.PathValues = pathValues
+
+		response, ok := e.next(recorded)
+		if !ok {
+			continue
+		}
+
+		if response.Err != nil {
+			return nil, response.Err
+		}
+
+		return response.toHTTP(req), nil
+	}
+
+	m.m.Lock()
+	m.unexpected = append(m.unexpected, recorded)
+	m.m.Unlock()
+
+	return Response{
+		StatusCode: http.StatusNotImplemented,
+		Body:       []byte(fmt.Sprintf("klienttest: no expectation for %s %s", req.Method, req.URL.Path)),
+	}.toHTTP(req), nil
+}
+
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	recorder := httptest.NewRecorder()
+	for k, v := range r.Header {
+		recorder.Header()[k] = v
+	}
+
+	statusCode := r.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+
+	recorder.WriteHeader(statusCode)
+	_, _ = recorder.Write(r.Body)
+
+	resp := recorder.Result()
+	resp.Request = req
+	resp.ContentLength = int64(len(r.Body))
+
+	return resp
+}
+
+// Expectation is a registered request matcher with responses.
+type Expectation struct {
+	method  string
+	pattern string
+	query   url.Values
+	header  http.Header
+	body    []func([]byte) bool
+
+	m         sync.Mutex
+	responses []func(*Request) Response
+	calls     int
+	// times is the expected call count, -1 means at least once.
+	times int
+}
+
+func (e *Expectation) String() string {
+	method := e.method
+	if method == "" {
+		method = "*"
+	}
+
+	return method + " " + e.pattern
+}
+
+// WithQuery matches the query parameter values.
+func (e *Expectation) WithQuery(key string, values ...string) *Expectation {
+	if e.query == nil {
+		e.query = url.Values{}
+	}
+
+	e.query[key] = append(e.query[key], values...)
+
+	return e
+}
+
+// WithHeader matches the header value.
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	if e.header == nil {
+		e.header = http.Header{}
+	}
+
+	e.header.Add(key, value)
+
+	return e
+}
+
+// WithJSONBody matches the request body semantically equal to json encoded v.
+func (e *Expectation) WithJSONBody(v any) *Expectation {
+	want, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("klienttest: marshal json body: %v", err))
+	}
+
+	var wantValue any
+	_ = json.Unmarshal(want, &wantValue)
+
+	return e.WithBody(func(body []byte) bool {
+		var got any
+		if err := json.Unmarshal(body, &got); err != nil {
+			return false
+		}
+
+		return reflect.DeepEqual(wantValue, got)
+	})
+}
+
+// WithBody matches the request body with the function.
+func (e *Expectation) WithBody(match func(body []byte) bool) *Expectation {
+	e.body = append(e.body, match)
+
+	return e
+}
+
+// Times sets the expected call count.
+func (e *Expectation) Times(n int) *Expectation {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	e.times = n
+
+	return e
+}
+
+// Once sets the expected call count to 1.
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+// Reply queues the response, last response is repeated when the queue is consumed.
+func (e *Expectation) Reply(statusCode int, body []byte, header http.Header) *Expectation {
+	return e.ReplyFunc(func(*Request) Response {
+		return Response{StatusCode: statusCode, Body: body, Header: header}
+	})
+}
+
+// ReplyJSON queues the json encoded response.
+func (e *Expectation) ReplyJSON(statusCode int, v any) *Expectation {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("klienttest: marshal json response: %v", err))
+	}
+
+	return e.Reply(statusCode, body, http.Header{"Content-Type": []string{"application/json"}})
+}
+
+// ReplyError queues the transport error.
+func (e *Expectation) ReplyError(err error) *Expectation {
+	return e.ReplyFunc(func(*Request) Response {
+		return Response{Err: err}
+	})
+}
+
+// ReplyFunc queues the templated response generated from the request.
+func (e *Expectation) ReplyFunc(fn func(*Request) Response) *Expectation {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	e.responses = append(e.responses, fn)
+
+	return e
+}
+
+// match reports whether the request matches and returns the path values.
+func (e *Expectation) match(r *Request) (map[string]string, bool) {
+	if e.method != "" && !strings.EqualFold(e.method, r.Method) {
+		return nil, false
+	}
+
+	pathValues, ok := matchPath(e.pattern, r.URL.Path)
+	if !ok {
+		return nil, false
+	}
+
+	query := r.URL.Query()
+	for k, values := range e.query {
+		for _, v := range values {
+			if !contains(query[k], v) {
+				return nil, false
+			}
+		}
+	}
+
+	for k, values := range e.header {
+		for _, v := range values {
+			if !contains(r.Header.Values(k), v) {
+				return nil, false
+			}
+		}
+	}
+
+	for _, match := range e.body {
+		if !match(r.Body) {
+			return nil, false
+		}
+	}
+
+	return pathValues, true
+}
+
+// next returns the next queued response, false if the call count is exhausted.
+func (e *Expectation) next(r *Request) (Response, bool) {
+	e.m.Lock()
+
+	if e.times >= 0 && e.calls >= e.times {
+		e.m.Unlock()
+
+		return Response{}, false
+	}
+
+	index := min(e.calls, len(e.responses)-1)
+	e.calls++
+
+	var fn func(*Request) Response
+	if index >= 0 {
+		fn = e.responses[index]
+	}
+
+	e.m.Unlock()
+
+	if fn == nil {
+		return Response{StatusCode: http.StatusOK}, true
+	}
+
+	return fn(r), true
+}
+
+// matchPath matches the path with the pattern and returns the path values.
+func matchPath(pattern, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	values := map[string]string{}
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "...}") {
+			if i > len(pathSegments) {
+				return nil, false
+			}
+
+			values[segment[1:len(segment)-4]] = strings.Join(pathSegments[i:], "/")
+
+			return values, true
+		}
+
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+
+			values[segment[1:len(segment)-1]] = pathSegments[i]
+
+			continue
+		}
+
+		if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	return values, true
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,117 @@
+package klienttest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/worldline-go/klient"
+)
+
+type recordTB struct {
+	testing.TB
+
+	errors   []string
+	cleanups []func()
+}
+
+func (r *recordTB) Helper() {}
+
+func (r *recordTB) Cleanup(fn func()) {
+	r.cleanups = append(r.cleanups, fn)
+}
+
+func (r *recordTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMock(t *testing.T) {
+	mock := NewMock(t)
+
+	mock.On(http.MethodGet, "/api/users/{id}").
+		WithQuery("expand", "true").
+		WithHeader("X-Info", "test").
+		ReplyFunc(func(r *Request) Response {
+			return Response{StatusCode: http.StatusOK, Body: []byte(`{"id":"` + r.PathValues["id"] + `"}`)}
+		}).
+		Times(2)
+
+	mock.On(http.MethodPost, "/api/users").
+		WithJSONBody(map[string]any{"name": "test"}).
+		ReplyJSON(http.StatusServiceUnavailable, map[string]string{"error": "retry"}).
+		ReplyJSON(http.StatusCreated, map[string]string{"id": "1"})
+
+	client, err := klient.New(
+		klient.WithBaseURL("http://example.com/api/"),
+		klient.WithBaseTransport(mock),
+		klient.WithRetryWaitMin(0),
+		klient.WithRetryWaitMax(0),
+		klient.WithHeaderSet(http.Header{"X-Info": []string{"test"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		var v map[string]string
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "users/"+id+"?expand=true", nil)
+		if err := client.Do(req, klient.ResponseFuncJSON(&v)); err != nil {
+			t.Fatal(err)
+		}
+
+		if v["id"] != id {
+			t.Fatalf("expected id %s, got %v", id, v)
+		}
+	}
+
+	var created map[string]string
+	if err := client.NewRequest(t.Context()).
+		Method(http.MethodPost).
+		Path("users").
+		JSON(map[string]string{"name": "test"}).
+		Do(klient.ResponseFuncJSON(&created)); err != nil {
+		t.Fatal(err)
+	}
+
+	if created["id"] != "1" {
+		t.Fatalf("expected created id, got %v", created)
+	}
+
+	requests := mock.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(requests))
+	}
+
+	var body map[string]string
+	if err := requests[3].JSON(&body); err != nil || body["name"] != "test" {
+		t.Fatalf("unexpected recorded body %v, %v", body, err)
+	}
+}
+
+func TestMock_Verify(t *testing.T) {
+	tb := &recordTB{TB: t}
+	mock := NewMock(tb)
+
+	mock.On(http.MethodGet, "/called").Once()
+	mock.On(http.MethodGet, "/not-called")
+	mock.On("", "/files/{path...}").ReplyError(errors.New("connection reset"))
+
+	client, err := klient.NewPlain(klient.WithBaseURL("http://example.com"), klient.WithBaseTransport(mock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/called", "/called", "/files/a/b"} {
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, path, nil)
+		_ = client.Do(req, func(*http.Response) error { return nil })
+	}
+
+	for _, fn := range tb.cleanups {
+		fn()
+	}
+
+	if len(tb.errors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", tb.errors)
+	}
+}
@@ -0,0 +1,306 @@
+package klienttest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/worldline-go/klient"
+)
+
+// CA is an ephemeral certificate authority for tests.
+type CA struct {
+	Certificate *x509.Certificate
+	Key         *ecdsa.PrivateKey
+	// PEM is the encoded certificate.
+	PEM []byte
+}
+
+// Cert is a certificate issued by the CA.
+type Cert struct {
+	Certificate *x509.Certificate
+	Key         *ecdsa.PrivateKey
+	// CertPEM and KeyPEM are the encoded certificate and PKCS#8 key.
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// CertOptions is the options of the issued certificate.
+type CertOptions struct {
+	// CommonName default is "klienttest".
+	CommonName string
+	// Hosts are the DNS names and IP addresses of the certificate.
+	Hosts []string
+	// NotBefore default is one hour ago, NotAfter default is one day later.
+	// Use a past NotAfter for an expired certificate.
+	NotBefore time.Time
+	NotAfter  time.Time
+	// Client issues a client authentication certificate instead of server.
+	Client bool
+}
+
+// NewCA generates a new CA, fails the test on error.
+func NewCA(t testing.TB) *CA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("klienttest: generate ca key: %v", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber(t),
+		Subject:               pkix.Name{CommonName: "klienttest CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("klienttest: create ca certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("klienttest: parse ca certificate: %v", err)
+	}
+
+	return &CA{
+		Certificate: cert,
+		Key:         key,
+		PEM:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// Issue returns a new certificate signed by the CA, fails the test on error.
+func (ca *CA) Issue(t testing.TB, o CertOptions) *Cert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("klienttest: generate key: %v", err)
+	}
+
+	if o.CommonName == "" {
+		o.CommonName = "klienttest"
+	}
+
+	now := time.Now()
+	if o.NotBefore.IsZero() {
+		o.NotBefore = now.Add(-time.Hour)
+	}
+
+	if o.NotAfter.IsZero() {
+		o.NotAfter = now.Add(24 * time.Hour)
+	}
+
+	extKeyUsage := x509.ExtKeyUsageServerAuth
+	if o.Client {
+		extKeyUsage = x509.ExtKeyUsageClientAuth
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber(t),
+		Subject:      pkix.Name{CommonName: o.CommonName},
+		NotBefore:    o.NotBefore,
+		NotAfter:     o.NotAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{extKeyUsage},
+	}
+
+	for _, host := range o.Hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Certificate, &key.PublicKey, ca.Key)
+	if err != nil {
+		t.Fatalf("klienttest: create certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("klienttest: parse certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("klienttest: marshal key: %v", err)
+	}
+
+	return &Cert{
+		Certificate: cert,
+		Key:         key,
+		CertPEM:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:      pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// Pool returns a certificate pool with the CA.
+func (ca *CA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Certificate)
+
+	return pool
+}
+
+// WriteFile writes the CA certificate to dir/ca.pem and returns the path.
+func (ca *CA) WriteFile(t testing.TB, dir string) string {
+	t.Helper()
+
+	return writeFile(t, filepath.Join(dir, "ca.pem"), ca.PEM)
+}
+
+// TLSCertificate returns the certificate with the key.
+func (c *Cert) TLSCertificate() tls.Certificate {
+	return tls.Certificate{
+		Certificate: [][]byte{c.Certificate.Raw},
+		PrivateKey:  c.Key,
+		Leaf:        c.Certificate,
+	}
+}
+
+// WriteFiles writes the certificate to dir/name.pem and the key to dir/name-key.pem.
+func (c *Cert) WriteFiles(t testing.TB, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+
+	certFile = writeFile(t, filepath.Join(dir, name+".pem"), c.CertPEM)
+	keyFile = writeFile(t, filepath.Join(dir, name+"-key.pem"), c.KeyPEM)
+
+	return certFile, keyFile
+}
+
+type optionTLSServerValue struct {
+	ServerCert CertOptions
+	ClientCert CertOptions
+	ClientAuth tls.ClientAuthType
+}
+
+type OptionTLSServerFn func(*optionTLSServerValue)
+
+// WithServerCert sets the server certificate options, default hosts are 127.0.0.1, ::1 and localhost.
+func WithServerCert(o CertOptions) OptionTLSServerFn {
+	return func(v *optionTLSServerValue) {
+		v.ServerCert = o
+	}
+}
+
+// WithClientCert sets the generated client certificate options.
+func WithClientCert(o CertOptions) OptionTLSServerFn {
+	return func(v *optionTLSServerValue) {
+		v.ClientCert = o
+	}
+}
+
+// WithClientAuth sets the client authentication policy of the server, default is tls.RequireAndVerifyClientCert.
+func WithClientAuth(clientAuth tls.ClientAuthType) OptionTLSServerFn {
+	return func(v *optionTLSServerValue) {
+		v.ClientAuth = clientAuth
+	}
+}
+
+// TLSServer is an httptest TLS server with generated CA, server and client certificates.
+//
+//	srv := klienttest.NewTLSServer(t, handler)
+//	client, err := klient.New(klient.WithBaseURL(srv.URL), klient.WithTLSConfig(&srv.Config))
+type TLSServer struct {
+	*httptest.Server
+
+	CA         *CA
+	ServerCert *Cert
+	ClientCert *Cert
+	// Dir is the temp directory of the written files.
+	Dir string
+	// Config is the client TLS config with the written CA, client certificate and key files.
+	Config klient.TLSConfig
+}
+
+// NewTLSServer starts a TLS server requiring client certificates signed by the generated CA.
+//
+// Server is closed on test cleanup.
+func NewTLSServer(t testing.TB, handler http.Handler, opts ...OptionTLSServerFn) *TLSServer {
+	t.Helper()
+
+	o := optionTLSServerValue{
+		ServerCert: CertOptions{Hosts: []string{"127.0.0.1", "::1", "localhost"}},
+		ClientAuth: tls.RequireAndVerifyClientCert,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	o.ServerCert.Client = false
+	o.ClientCert.Client = true
+
+	ca := NewCA(t)
+	serverCert := ca.Issue(t, o.ServerCert)
+	clientCert := ca.Issue(t, o.ClientCert)
+
+	dir := t.TempDir()
+	caFile := ca.WriteFile(t, dir)
+	certFile, keyFile := clientCert.WriteFiles(t, dir, "client")
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert.TLSCertificate()},
+		ClientCAs:    ca.Pool(),
+		ClientAuth:   o.ClientAuth,
+		MinVersion:   tls.VersionTLS12,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return &TLSServer{
+		Server:     srv,
+		CA:         ca,
+		ServerCert: serverCert,
+		ClientCert: clientCert,
+		Dir:        dir,
+		Config: klient.TLSConfig{
+			CertFile: certFile,
+			KeyFile:  keyFile,
+			CAFile:   caFile,
+		},
+	}
+}
+
+func serialNumber(t testing.TB) *big.Int {
+	t.Helper()
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		t.Fatalf("klienttest: generate serial number: %v", err)
+	}
+
+	return serial
+}
+
+func writeFile(t testing.TB, path string, data []byte) string {
+	t.Helper()
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("klienttest: write %s: %v", path, err)
+	}
+
+	return path
+}
@@ -0,0 +1,459 @@
+package klienttest
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/worldline-go/klient"
+	"github.com/worldline-go/logz"
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestTLSServer(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	})
+
+	srv := NewTLSServer(t, handler, WithClientCert(CertOptions{CommonName: "payments"}))
+
+	get := func(t *testing.T, url string, tlsConfig klient.TLSConfig) (string, error) {
+		t.Helper()
+
+		client, err := klient.NewPlain(klient.WithTLSConfig(&tlsConfig))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, url, nil)
+
+		var body string
+		err = client.Do(req, func(resp *http.Response) error {
+			b, err := io.ReadAll(resp.Body)
+			body = string(b)
+
+			return err
+		})
+
+		return body, err
+	}
+
+	t.Run("mtls", func(t *testing.T) {
+		body, err := get(t, srv.URL, srv.Config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if body != "payments" {
+			t.Errorf("unexpected client common name %q", body)
+		}
+	})
+
+	t.Run("without client cert", func(t *testing.T) {
+		if _, err := get(t, srv.URL, klient.TLSConfig{CAFile: srv.Config.CAFile}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("expired client cert", func(t *testing.T) {
+		cert := srv.CA.Issue(t, CertOptions{Client: true, NotAfter: time.Now().Add(-time.Minute)})
+		certFile, keyFile := cert.WriteFiles(t, t.TempDir(), "expired")
+
+		if _, err := get(t, srv.URL, klient.TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: srv.Config.CAFile}); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+
+	t.Run("wrong ca", func(t *testing.T) {
+		caFile := NewCA(t).WriteFile(t, t.TempDir())
+
+		_, err := get(t, srv.URL, klient.TLSConfig{CertFile: srv.Config.CertFile, KeyFile: srv.Config.KeyFile, CAFile: caFile})
+		if err == nil || !strings.Contains(err.Error(), "certificate") {
+			t.Fatalf("expected certificate error, got %v", err)
+		}
+	})
+}
+
+func TestTLSServerHostnameMismatch(t *testing.T) {
+	srv := NewTLSServer(t, http.NotFoundHandler(), WithServerCert(CertOptions{Hosts: []string{"example.com"}}))
+
+	client, err := klient.NewPlain(klient.WithTLSConfig(&srv.Config))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+
+	err = client.Do(req, func(*http.Response) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "127.0.0.1") {
+		t.Fatalf("expected hostname error, got %v", err)
+	}
+}
+
+func TestTLSReload(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	})
+
+	srv := NewTLSServer(t, handler, WithClientCert(CertOptions{CommonName: "first"}))
+
+	dir := t.TempDir()
+	certFile, keyFile := srv.ClientCert.WriteFiles(t, dir, "client")
+	caFile := NewCA(t).WriteFile(t, dir)
+
+	modified := time.Now()
+	touch := func(files ...string) {
+		modified = modified.Add(time.Second)
+
+		for _, file := range files {
+			if err := os.Chtimes(file, modified, modified); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	var (
+		mutex      sync.Mutex
+		reloadErrs []error
+	)
+
+	client, err := klient.NewPlain(
+		klient.WithTLSConfig(&klient.TLSConfig{CertFile: certFile, KeyFile: keyFile, CAFile: caFile, ReloadInterval: time.Millisecond}),
+		klient.WithTLSReloadHook(func(err error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			reloadErrs = append(reloadErrs, err)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() (string, error) {
+		// new connection for a new handshake after the interval
+		client.HTTP.CloseIdleConnections()
+		time.Sleep(2 * time.Millisecond)
+
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+
+		var body string
+		err := client.Do(req, func(resp *http.Response) error {
+			b, err := io.ReadAll(resp.Body)
+			body = string(b)
+
+			return err
+		})
+
+		return body, err
+	}
+
+	if _, err := get(); err == nil {
+		t.Fatal("expected certificate error of the wrong ca")
+	}
+
+	srv.CA.WriteFile(t, dir)
+	touch(caFile)
+
+	if body, err := get(); err != nil || body != "first" {
+		t.Fatalf("expected first certificate with reloaded ca, got %q %v", body, err)
+	}
+
+	srv.CA.Issue(t, CertOptions{CommonName: "second", Client: true}).WriteFiles(t, dir, "client")
+	touch(certFile, keyFile)
+
+	if body, err := get(); err != nil || body != "second" {
+		t.Fatalf("expected rotated certificate, got %q %v", body, err)
+	}
+
+	if err := os.WriteFile(certFile, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	touch(certFile)
+
+	if body, err := get(); err != nil || body != "second" {
+		t.Fatalf("expected last good certificate, got %q %v", body, err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(reloadErrs) == 0 || !errors.Is(reloadErrs[0], klient.ErrTLSReload) {
+		t.Errorf("expected reload errors, got %v", reloadErrs)
+	}
+}
+
+func TestTLSConfigSources(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	})
+
+	srv := NewTLSServer(t, handler,
+		WithServerCert(CertOptions{Hosts: []string{"127.0.0.1", "api.example.com"}}),
+		WithClientCert(CertOptions{CommonName: "payments"}),
+	)
+
+	encryptedKey, err := pkcs8.MarshalPrivateKey(srv.ClientCert.Key, []byte("secret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundle, err := pkcs12.Modern.Encode(srv.ClientCert.Key, srv.ClientCert.Certificate, nil, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundleFile := filepath.Join(t.TempDir(), "client.p12")
+	if err := os.WriteFile(bundleFile, bundle, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config klient.TLSConfig
+		err    string
+	}{
+		{
+			name:   "inline pem",
+			config: klient.TLSConfig{Cert: string(srv.ClientCert.CertPEM), Key: string(srv.ClientCert.KeyPEM), CA: string(srv.CA.PEM)},
+		},
+		{
+			name: "encrypted key",
+			config: klient.TLSConfig{
+				Cert:        string(srv.ClientCert.CertPEM),
+				Key:         string(pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: encryptedKey})),
+				KeyPassword: "secret",
+				CAFile:      srv.Config.CAFile,
+			},
+		},
+		{
+			name:   "pkcs12 file",
+			config: klient.TLSConfig{PKCS12File: bundleFile, PKCS12Password: "secret", CAFile: srv.Config.CAFile},
+		},
+		{
+			name:   "pkcs12 inline",
+			config: klient.TLSConfig{PKCS12: base64.StdEncoding.EncodeToString(bundle), PKCS12Password: "secret", CAFile: srv.Config.CAFile, DisableSystemCA: true},
+		},
+		{
+			name:   "server name",
+			config: klient.TLSConfig{CertFile: srv.Config.CertFile, KeyFile: srv.Config.KeyFile, CAFile: srv.Config.CAFile, ServerName: "api.example.com"},
+		},
+		{
+			name:   "wrong server name",
+			config: klient.TLSConfig{CertFile: srv.Config.CertFile, KeyFile: srv.Config.KeyFile, CAFile: srv.Config.CAFile, ServerName: "other.example.com"},
+			err:    "other.example.com",
+		},
+		{
+			name:   "version",
+			config: klient.TLSConfig{CertFile: srv.Config.CertFile, KeyFile: srv.Config.KeyFile, CAFile: srv.Config.CAFile, MaxVersion: "1.2", CipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}},
+		},
+		{
+			name:   "wrong pkcs12 password",
+			config: klient.TLSConfig{PKCS12File: bundleFile, PKCS12Password: "wrong", CAFile: srv.Config.CAFile},
+			err:    "pkcs12",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := klient.NewPlain(klient.WithTLSConfig(&tt.config))
+			if err == nil {
+				req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+				err = client.Do(req, func(resp *http.Response) error {
+					version := uint16(tls.VersionTLS13)
+					if tt.config.MaxVersion == "1.2" {
+						version = tls.VersionTLS12
+					}
+
+					if resp.TLS.Version != version {
+						t.Errorf("unexpected tls version %x", resp.TLS.Version)
+					}
+
+					b, err := io.ReadAll(resp.Body)
+					if err == nil && string(b) != "payments" {
+						t.Errorf("unexpected client common name %q", b)
+					}
+
+					return err
+				})
+			}
+
+			if tt.err == "" && err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("expected error with %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// warnLogger records the warn messages.
+type warnLogger struct {
+	logz.AdapterNoop
+
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (l *warnLogger) Warn(msg string, _ ...any) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.messages = append(l.messages, msg)
+}
+
+func TestTLSPinning(t *testing.T) {
+	srv := NewTLSServer(t, http.NotFoundHandler())
+
+	leafPin := klient.SPKIPin(srv.ServerCert.Certificate)
+	caPin := "sha256/" + klient.SPKIPin(srv.CA.Certificate)
+	otherPin := klient.SPKIPin(NewCA(t).Certificate)
+
+	tests := []struct {
+		name       string
+		pins       []string
+		backupPins []string
+		reportOnly bool
+		insecure   bool
+		reload     bool
+		mismatch   bool
+	}{
+		{name: "leaf", pins: []string{leafPin}},
+		{name: "ca", pins: []string{caPin}},
+		{name: "leaf reload", pins: []string{leafPin}, reload: true},
+		{name: "ca reload", pins: []string{caPin}, reload: true},
+		{name: "mismatch reload", pins: []string{otherPin}, reload: true, mismatch: true},
+		{name: "ca insecure", pins: []string{caPin}, insecure: true, mismatch: true},
+		{name: "backup", pins: []string{otherPin}, backupPins: []string{leafPin}},
+		{name: "mismatch", pins: []string{otherPin}, mismatch: true},
+		{name: "mismatch insecure", pins: []string{otherPin}, insecure: true, mismatch: true},
+		{name: "report only", pins: []string{otherPin}, reportOnly: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig := srv.Config
+			tlsConfig.Pins = tt.pins
+			tlsConfig.BackupPins = tt.backupPins
+			tlsConfig.PinsReportOnly = tt.reportOnly
+
+			if tt.reload {
+				tlsConfig.ReloadInterval = time.Minute
+			}
+
+			logger := &warnLogger{}
+
+			client, err := klient.New(
+				klient.WithDisableBaseURLCheck(true),
+				klient.WithDisableEnvValues(true),
+				klient.WithTLSConfig(&tlsConfig),
+				klient.WithInsecureSkipVerify(tt.insecure),
+				klient.WithLogger(logger),
+				klient.WithRetryWaitMin(time.Millisecond),
+				klient.WithRetryWaitMax(time.Millisecond),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+			err = client.Do(req, func(*http.Response) error { return nil })
+
+			var pinErr *klient.PinError
+			if errors.As(err, &pinErr) != tt.mismatch {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if tt.mismatch {
+				if !errors.Is(err, klient.ErrPinMismatch) || pinErr.Pins[0] != leafPin {
+					t.Errorf("unexpected pin error %v", err)
+				}
+
+				var verifyErr *tls.CertificateVerificationError
+				if errors.As(err, &verifyErr) {
+					t.Errorf("pin error is a verification error %v", err)
+				}
+			}
+
+			logger.mutex.Lock()
+			defer logger.mutex.Unlock()
+
+			if tt.reportOnly != slices.Contains(logger.messages, "certificate pin mismatch") {
+				t.Errorf("unexpected logs %v", logger.messages)
+			}
+
+			if slices.Contains(logger.messages, "retrying request") {
+				t.Error("pin mismatch retried")
+			}
+		})
+	}
+}
+
+func TestTLSPinningChain(t *testing.T) {
+	pinned := NewCA(t)
+	other := NewCA(t)
+
+	// server of an other trusted CA sends the pinned CA as an extra chain certificate
+	cert := other.Issue(t, CertOptions{Hosts: []string{"127.0.0.1"}}).TLSCertificate()
+	cert.Certificate = append(cert.Certificate, pinned.Certificate.Raw)
+
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		reload   bool
+		insecure bool
+	}{
+		{name: "verified"},
+		{name: "reload", reload: true},
+		{name: "insecure", insecure: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig := klient.TLSConfig{
+				CA:   string(pinned.PEM) + string(other.PEM),
+				Pins: []string{klient.SPKIPin(pinned.Certificate)},
+			}
+
+			if tt.reload {
+				tlsConfig.ReloadInterval = time.Minute
+			}
+
+			client, err := klient.New(
+				klient.WithDisableBaseURLCheck(true),
+				klient.WithDisableEnvValues(true),
+				klient.WithTLSConfig(&tlsConfig),
+				klient.WithInsecureSkipVerify(tt.insecure),
+				klient.WithDisableRetry(true),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+			err = client.Do(req, func(*http.Response) error { return nil })
+
+			if !errors.Is(err, klient.ErrPinMismatch) {
+				t.Fatalf("expected pin mismatch, got %v", err)
+			}
+		})
+	}
+}
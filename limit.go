@@ -0,0 +1,94 @@
+package klient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var ErrResponseTooLarge = errors.New("response body too large")
+
+const CtxKeyMaxResponseBodySize ctxKey = "max_response_body_size"
+
+// CtxWithMaxResponseBodySize overrides the maximum response body size of the client for the request.
+//
+// Zero or negative size disables the limit.
+func CtxWithMaxResponseBodySize(ctx context.Context, size int64) context.Context {
+	return context.WithValue(ctx, CtxKeyMaxResponseBodySize, size)
+}
+
+// maxBodyTransport limits the response body size.
+//
+// 2xx responses with bigger Content-Length are rejected before reading,
+// error responses are only limited since ResponseErrLimit already bounds reading them.
+// Downloads and server-sent events are not limited by the client size, only by CtxWithMaxResponseBodySize.
+type maxBodyTransport struct {
+	base http.RoundTripper
+	max  int64
+}
+
+var _ http.RoundTripper = (*maxBodyTransport)(nil)
+
+func (t *maxBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp == nil || resp.Body == nil {
+		return resp, err
+	}
+
+	limit := t.max
+	if stream, _ := req.Context().Value(ctxKeyStream).(bool); stream {
+		limit = 0
+	}
+
+	if v, ok := req.Context().Value(CtxKeyMaxResponseBodySize).(int64); ok {
+		limit = v
+	}
+
+	if limit <= 0 {
+		return resp, nil
+	}
+
+	if resp.ContentLength > limit && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_ = resp.Body.Close()
+
+		return nil, fmt.Errorf("%w: content length %d exceeds limit %d", ErrResponseTooLarge, resp.ContentLength, limit)
+	}
+
+	resp.Body = &maxBodyReader{body: resp.Body, remaining: limit, limit: limit}
+
+	return resp, nil
+}
+
+// maxBodyReader returns ErrResponseTooLarge when reading beyond the limit.
+type maxBodyReader struct {
+	body      io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (r *maxBodyReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		// check there is more content
+		var b [1]byte
+		if n, _ := r.body.Read(b[:]); n > 0 {
+			return 0, fmt.Errorf("%w: exceeds limit %d", ErrResponseTooLarge, r.limit)
+		}
+
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.body.Read(p)
+	r.remaining -= int64(n)
+
+	return n, err
+}
+
+func (r *maxBodyReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,67 @@
+package klient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxResponseBodySize(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := strings.Repeat("x", 100)
+
+		switch r.URL.Path {
+		case "/chunked":
+			w.(http.Flusher).Flush()
+		case "/error":
+			w.WriteHeader(http.StatusBadRequest)
+		}
+
+		_, _ = io.WriteString(w, body)
+	}))
+	defer httpServer.Close()
+
+	client, err := New(WithBaseURL(httpServer.URL), WithMaxResponseBodySize(50), WithRetryMax(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(resp *http.Response) error {
+		_, err := io.ReadAll(resp.Body)
+
+		return err
+	}
+
+	t.Run("content length", func(t *testing.T) {
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/length", nil)
+		if err := client.Do(req, read); !errors.Is(err, ErrResponseTooLarge) {
+			t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("chunked", func(t *testing.T) {
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/chunked", nil)
+		if err := client.Do(req, read); !errors.Is(err, ErrResponseTooLarge) {
+			t.Fatalf("expected ErrResponseTooLarge, got %v", err)
+		}
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/error", nil)
+
+		var respErr *ResponseError
+		if err := client.Do(req, UnexpectedResponse); !errors.As(err, &respErr) || len(respErr.Body) != 50 {
+			t.Fatalf("expected limited response error, got %v", err)
+		}
+	})
+
+	t.Run("context override", func(t *testing.T) {
+		req, _ := http.NewRequestWithContext(CtxWithMaxResponseBodySize(t.Context(), 100), http.MethodGet, "/chunked", nil)
+		if err := client.Do(req, read); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
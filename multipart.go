@@ -0,0 +1,414 @@
+package klient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrMultipartNotReopenable = errors.New("multipart part is not reopenable")
+	ErrMultipartSeek          = errors.New("multipart body only seeks to start")
+)
+
+// Multipart is a streaming multipart/form-data body builder.
+//
+// Parts are written to the request body with io.Pipe when the body is read,
+// nothing is buffered in memory.
+// When all parts are reopenable (fields, files, byte slices) the request body
+// can be regenerated for retries and redirects.
+//
+//	req, err := klient.NewMultipart().
+//		Field("name", "report").
+//		File("document", "/tmp/report.pdf").
+//		Progress(func(written, total int64) { ... }).
+//		Request(ctx, http.MethodPost, "/upload")
+type Multipart struct {
+	parts    []*multipartPart
+	progress func(written, total int64)
+	boundary string
+	err      error
+}
+
+type multipartPart struct {
+	header textproto.MIMEHeader
+	// size is the content size, -1 if unknown.
+	size int64
+	// open returns the content of the part.
+	open func() (io.ReadCloser, error)
+	// reopenable is true when open can be called multiple times.
+	reopenable bool
+}
+
+type optionPartValue struct {
+	FileName    string
+	ContentType string
+	Header      textproto.MIMEHeader
+}
+
+type OptionPartFn func(*optionPartValue)
+
+type OptionPartHolder struct{}
+
+var OptionPart = OptionPartHolder{}
+
+// WithContentType sets the Content-Type of the part.
+func (OptionPartHolder) WithContentType(contentType string) OptionPartFn {
+	return func(o *optionPartValue) {
+		o.ContentType = contentType
+	}
+}
+
+// WithFileName overrides the file name of the part.
+func (OptionPartHolder) WithFileName(fileName string) OptionPartFn {
+	return func(o *optionPartValue) {
+		o.FileName = fileName
+	}
+}
+
+// WithHeader adds a header to the part.
+func (OptionPartHolder) WithHeader(key, value string) OptionPartFn {
+	return func(o *optionPartValue) {
+		if o.Header == nil {
+			o.Header = make(textproto.MIMEHeader)
+		}
+
+		o.Header.Add(key, value)
+	}
+}
+
+// NewMultipart returns a new multipart/form-data body builder.
+func NewMultipart() *Multipart {
+	return &Multipart{
+		boundary: multipart.NewWriter(nil).Boundary(),
+	}
+}
+
+// Field adds a form field.
+func (m *Multipart) Field(name, value string, opts ...OptionPartFn) *Multipart {
+	m.add(name, opts, &multipartPart{
+		size: int64(len(value)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(strings.NewReader(value)), nil
+		},
+		reopenable: true,
+	})
+
+	return m
+}
+
+// Bytes adds a file part with the content of data.
+func (m *Multipart) Bytes(name, fileName string, data []byte, opts ...OptionPartFn) *Multipart {
+	m.add(name, append([]OptionPartFn{OptionPart.WithFileName(fileName)}, opts...), &multipartPart{
+		size: int64(len(data)),
+		open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		},
+		reopenable: true,
+	})
+
+	return m
+}
+
+// File adds a file part from the disk, file is opened when the body is read.
+//
+// File name is the base of the path and content type is detected with the extension.
+func (m *Multipart) File(name, path string, opts ...OptionPartFn) *Multipart {
+	info, err := os.Stat(path)
+	if err != nil {
+		m.err = errors.Join(m.err, fmt.Errorf("multipart file %q: %w", name, err))
+
+		return m
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	opts = append([]OptionPartFn{
+		OptionPart.WithFileName(filepath.Base(path)),
+		OptionPart.WithContentType(contentType),
+	}, opts...)
+
+	m.add(name, opts, &multipartPart{
+		size: info.Size(),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+		reopenable: true,
+	})
+
+	return m
+}
+
+// Reader adds a file part with the content of r.
+//
+// The reader is read once, so the body cannot be regenerated for retries.
+// Use ReaderFunc for reopenable readers.
+func (m *Multipart) Reader(name, fileName string, r io.Reader, opts ...OptionPartFn) *Multipart {
+	var once sync.Once
+
+	m.add(name, append([]OptionPartFn{OptionPart.WithFileName(fileName)}, opts...), &multipartPart{
+		size: -1,
+		open: func() (io.ReadCloser, error) {
+			err := ErrMultipartNotReopenable
+			once.Do(func() { err = nil })
+
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", err, name)
+			}
+
+			if rc, ok := r.(io.ReadCloser); ok {
+				return rc, nil
+			}
+
+			return io.NopCloser(r), nil
+		},
+	})
+
+	return m
+}
+
+// ReaderFunc adds a file part which content is opened with open function each time the body is generated.
+//
+// Size is the content size for Content-Length, -1 if unknown.
+func (m *Multipart) ReaderFunc(name, fileName string, size int64, open func() (io.ReadCloser, error), opts ...OptionPartFn) *Multipart {
+	m.add(name, append([]OptionPartFn{OptionPart.WithFileName(fileName)}, opts...), &multipartPart{
+		size:       size,
+		open:       open,
+		reopenable: true,
+	})
+
+	return m
+}
+
+// Progress sets the callback to report written bytes of the body.
+//
+// Total is -1 when the body size is unknown.
+// It is reset to zero when the body regenerated for a retry.
+func (m *Multipart) Progress(fn func(written, total int64)) *Multipart {
+	m.progress = fn
+
+	return m
+}
+
+// ContentType returns the Content-Type header value with the boundary.
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// Request returns a new request with the multipart body.
+//
+// GetBody is set when all parts are reopenable.
+func (m *Multipart) Request(ctx context.Context, method, url string) (*http.Request, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	contentLength, err := m.contentLength()
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", m.ContentType())
+	req.ContentLength = contentLength
+	req.Body = m.body(contentLength)
+
+	if m.reopenable() {
+		req.GetBody = func() (io.ReadCloser, error) {
+			return m.body(contentLength), nil
+		}
+	}
+
+	return req, nil
+}
+
+func (m *Multipart) add(name string, opts []OptionPartFn, part *multipartPart) {
+	o := optionPartValue{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	header := make(textproto.MIMEHeader)
+	params := map[string]string{"name": name}
+	if o.FileName != "" {
+		params["filename"] = o.FileName
+	}
+
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", params))
+
+	if o.ContentType != "" {
+		header.Set("Content-Type", o.ContentType)
+	}
+
+	for k, v := range o.Header {
+		header[k] = append(header[k], v...)
+	}
+
+	part.header = header
+	m.parts = append(m.parts, part)
+}
+
+func (m *Multipart) reopenable() bool {
+	for _, part := range m.parts {
+		if !part.reopenable {
+			return false
+		}
+	}
+
+	return true
+}
+
+// contentLength returns the size of the body, -1 if any part size is unknown.
+func (m *Multipart) contentLength() (int64, error) {
+	counter := &countWriter{}
+
+	w := multipart.NewWriter(counter)
+	if err := w.SetBoundary(m.boundary); err != nil {
+		return 0, err
+	}
+
+	for _, part := range m.parts {
+		if part.size < 0 {
+			return -1, nil
+		}
+
+		if _, err := w.CreatePart(part.header); err != nil {
+			return 0, err
+		}
+
+		counter.n += part.size
+	}
+
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+
+	return counter.n, nil
+}
+
+func (m *Multipart) body(total int64) io.ReadCloser {
+	body := &multipartBody{
+		m:     m,
+		total: total,
+	}
+
+	if m.reopenable() {
+		// retryablehttp rewinds io.ReadSeeker bodies instead of buffering them
+		return &multipartSeekBody{body}
+	}
+
+	return body
+}
+
+// write writes all parts to w.
+func (m *Multipart) write(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for _, part := range m.parts {
+		pw, err := mw.CreatePart(part.header)
+		if err != nil {
+			return err
+		}
+
+		content, err := part.open()
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(pw, content)
+		_ = content.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return mw.Close()
+}
+
+type multipartBody struct {
+	m       *Multipart
+	pr      *io.PipeReader
+	written int64
+	total   int64
+}
+
+func (b *multipartBody) Read(p []byte) (int, error) {
+	if b.pr == nil {
+		pr, pw := io.Pipe()
+		b.pr = pr
+
+		go func() {
+			pw.CloseWithError(b.m.write(pw))
+		}()
+	}
+
+	n, err := b.pr.Read(p)
+	if n > 0 {
+		b.written += int64(n)
+
+		if b.m.progress != nil {
+			b.m.progress(b.written, b.total)
+		}
+	}
+
+	return n, err
+}
+
+func (b *multipartBody) Close() error {
+	if b.pr != nil {
+		return b.pr.Close()
+	}
+
+	return nil
+}
+
+type multipartSeekBody struct {
+	*multipartBody
+}
+
+// Seek only supports rewinding to the start, the body is regenerated on next read.
+func (b *multipartSeekBody) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, ErrMultipartSeek
+	}
+
+	if err := b.Close(); err != nil {
+		return 0, err
+	}
+
+	b.pr = nil
+	b.written = 0
+
+	return 0, nil
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+
+	return len(p), nil
+}
@@ -0,0 +1,137 @@
+package klient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMultipart(t *testing.T) {
+	var attempts atomic.Int32
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			_, _ = io.Copy(io.Discard, r.Body)
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		if r.ContentLength <= 0 {
+			w.WriteHeader(http.StatusLengthRequired)
+
+			return
+		}
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+
+			return
+		}
+
+		file, header, err := r.FormFile("document")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(err.Error()))
+
+			return
+		}
+		defer file.Close()
+
+		content, _ := io.ReadAll(file)
+
+		_, _ = w.Write([]byte(strings.Join([]string{
+			r.FormValue("name"),
+			header.Filename,
+			header.Header.Get("Content-Type"),
+			string(content),
+			r.MultipartForm.File["raw"][0].Header.Get("X-Part"),
+		}, "|")))
+	}))
+	defer httpServer.Close()
+
+	path := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(path, []byte("report content"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := New(
+		WithBaseURL(httpServer.URL),
+		WithRetryWaitMin(10*time.Millisecond),
+		WithRetryWaitMax(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var written, total int64
+	req, err := NewMultipart().
+		Field("name", "test").
+		File("document", path).
+		Bytes("raw", "raw.bin", []byte{1, 2, 3}, OptionPart.WithHeader("X-Part", "extra")).
+		Progress(func(w, t int64) {
+			written, total = w, t
+		}).
+		Request(t.Context(), http.MethodPost, "/upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.GetBody == nil {
+		t.Fatal("expected GetBody to be set")
+	}
+
+	var result string
+	if err := client.Do(req, func(resp *http.Response) error {
+		if err := UnexpectedResponse(resp); err != nil {
+			return err
+		}
+
+		v, err := io.ReadAll(resp.Body)
+		result = string(v)
+
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "test|report.txt|text/plain; charset=utf-8|report content|extra"; result != want {
+		t.Fatalf("expected %q, got %q", want, result)
+	}
+
+	if attempts.Load() != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts.Load())
+	}
+
+	if written != req.ContentLength || total != req.ContentLength {
+		t.Fatalf("expected progress %d, got %d/%d", req.ContentLength, written, total)
+	}
+}
+
+func TestMultipart_Reader(t *testing.T) {
+	req, err := NewMultipart().
+		Reader("stream", "stream.txt", strings.NewReader("data")).
+		Request(t.Context(), http.MethodPost, "http://example.com/upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if req.GetBody != nil {
+		t.Fatal("expected GetBody to be nil")
+	}
+
+	if req.ContentLength != -1 {
+		t.Fatalf("expected unknown content length, got %d", req.ContentLength)
+	}
+
+	if _, ok := req.Body.(io.Seeker); ok {
+		t.Fatal("expected not seekable body")
+	}
+}
@@ -23,10 +23,28 @@ type optionClientValue struct {
 	RoundTripperList []RoundTripperFunc
 	// BaseTransport for change base transport of http client.
 	BaseTransport http.RoundTripper
+	// sharedTransport is the configured base transport of the registry, used as is.
+	sharedTransport *http.Transport
+	// ownedTransport is the base transport created by klient, pool and timeout options are applied only to it.
+	ownedTransport *http.Transport
 	// Ctx for RoundTripper.
 	Ctx context.Context
-	// MaxConnections is the maximum number of idle (keep-alive) connections.
+	// MaxConnections is the maximum number of idle (keep-alive) connections, also per host.
 	MaxConnections int
+	// MaxConnsPerHost limits the total connections per host, 0 is no limit.
+	MaxConnsPerHost int
+	// DialTimeout is the timeout of establishing a connection.
+	DialTimeout time.Duration
+	// KeepAlive is the interval of keep-alive probes, negative disables them.
+	KeepAlive time.Duration
+	// IdleConnTimeout is the time an idle connection stays in the pool.
+	IdleConnTimeout time.Duration
+	// TLSHandshakeTimeout is the timeout of the TLS handshake.
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout is the timeout of waiting response headers after writing the request.
+	ResponseHeaderTimeout time.Duration
+	// ExpectContinueTimeout is the wait of the first response headers with "Expect: 100-continue".
+	ExpectContinueTimeout time.Duration
 	// Logger is the customer logger instance of retryablehttp.
 	Logger logz.Adapter
 	// InsecureSkipVerify is the flag to skip TLS verification.
@@ -58,6 +76,8 @@ type optionClientValue struct {
 	RetryPolicy retryablehttp.CheckRetry
 	// Backoff is the backoff policy.
 	Backoff retryablehttp.Backoff
+	// Clock is the time source of the timers, default is the system clock.
+	Clock Clock
 	// RetryLog is the flag to enable retry log of the http body. Default is true.
 	RetryLog bool
 	// RetryTimeout is the timeout for each individual retry attempt.
@@ -66,8 +86,16 @@ type optionClientValue struct {
 	RetryTimeout time.Duration
 	// OptionRetryFns is the retry options for default retry policy.
 	OptionRetryFns []OptionRetryFn
+	// RetryBackoff is the backoff type replacing Backoff when set.
+	RetryBackoff BackoffType
+	// RetryJitter is the jitter factor of RetryBackoff.
+	RetryJitter float64
+	// RetryMethods limits the retried request methods, empty retries all methods.
+	RetryMethods []string
 	// DisableEnvValues is the flag to disable all env values check.
 	DisableEnvValues bool
+	// Name of the client to read KLIENT_<NAME>_* env values.
+	Name string
 
 	// Proxy for http(s) requests. Not used for http2.
 	Proxy string
@@ -80,6 +108,25 @@ type optionClientValue struct {
 
 	// TLSConfig is the TLS configuration.
 	TLSConfig *TLSConfig
+	// TLSReloadHook is the metric hook called with the errors of TLSConfig.ReloadInterval reloads.
+	TLSReloadHook func(error)
+
+	// CompressionEncoding is the request body compression, gzip or zstd.
+	CompressionEncoding string
+	// CompressionMinSize is the minimum request body size to compress.
+	CompressionMinSize int64
+	// AcceptEncoding is the list of accepted response encodings.
+	AcceptEncoding []string
+	// DisableDecompression is the flag to disable response decompression.
+	DisableDecompression bool
+
+	// MaxResponseBodySize is the maximum response body size, zero means no limit.
+	MaxResponseBodySize int64
+
+	// BodyReplay is the handling of not rewindable request bodies for retries.
+	BodyReplay BodyReplayMode
+	// BodyReplayMemoryLimit is the in memory buffer size of BodyReplayBuffer.
+	BodyReplayMemoryLimit int64
 }
 
 func OptionsPre(opts []OptionClientFn, preOpts ...OptionClientFn) []OptionClientFn {
@@ -117,9 +164,11 @@ func WithCtx(ctx context.Context) OptionClientFn {
 }
 
 // WithHeader configures the client to use this default header if not exist.
+//
+// Header is copied, options are applied again by Reload and WithHeaderAdd must not change the given one.
 func WithHeader(header http.Header) OptionClientFn {
 	return func(o *optionClientValue) {
-		o.Header = header
+		o.Header = header.Clone()
 	}
 }
 
@@ -162,12 +211,69 @@ func WithHeaderDel(keys ...string) OptionClientFn {
 }
 
 // WithMaxConnections configures the client to use the provided maximum number of idle connections.
+//
+// It is applied to the total and per host idle connections of the *http.Transport. Default is 100.
+//
+// Pool and timeout options are applied to the transport created by klient,
+// transports given with WithHTTPClient or WithBaseTransport are not changed.
 func WithMaxConnections(maxConnections int) OptionClientFn {
 	return func(o *optionClientValue) {
 		o.MaxConnections = maxConnections
 	}
 }
 
+// WithMaxConnsPerHost limits the total number of connections per host, including in use ones.
+// Default is no limit.
+func WithMaxConnsPerHost(maxConnsPerHost int) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.MaxConnsPerHost = maxConnsPerHost
+	}
+}
+
+// WithDialTimeout sets the timeout of establishing a connection. Default is 30s.
+func WithDialTimeout(timeout time.Duration) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.DialTimeout = timeout
+	}
+}
+
+// WithKeepAlive sets the interval of TCP keep-alive probes, negative disables them. Default is 30s.
+func WithKeepAlive(keepAlive time.Duration) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.KeepAlive = keepAlive
+	}
+}
+
+// WithIdleConnTimeout sets the time an idle connection stays in the pool. Default is 90s.
+func WithIdleConnTimeout(timeout time.Duration) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.IdleConnTimeout = timeout
+	}
+}
+
+// WithTLSHandshakeTimeout sets the timeout of the TLS handshake. Default is 10s.
+func WithTLSHandshakeTimeout(timeout time.Duration) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.TLSHandshakeTimeout = timeout
+	}
+}
+
+// WithResponseHeaderTimeout sets the timeout of waiting the response headers after writing the request.
+// Default is no timeout.
+func WithResponseHeaderTimeout(timeout time.Duration) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.ResponseHeaderTimeout = timeout
+	}
+}
+
+// WithExpectContinueTimeout sets the wait of the first response headers for requests with "Expect: 100-continue".
+// Default is 1s.
+func WithExpectContinueTimeout(timeout time.Duration) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.ExpectContinueTimeout = timeout
+	}
+}
+
 // WithLogger configures the client to use the provided logger.
 //
 // For zerolog logz.AdapterKV{Log: logger} can usable.
@@ -242,6 +348,15 @@ func WithBackoff(backoff retryablehttp.Backoff) OptionClientFn {
 	}
 }
 
+// WithClock configures the time source of retry waits, retry timeouts and reconnect waits.
+//
+// Use klienttest.Clock to test backoff schedules without waiting.
+func WithClock(clock Clock) OptionClientFn {
+	return func(options *optionClientValue) {
+		options.Clock = clock
+	}
+}
+
 // WithRetryPolicy configures the client to use the provided retry policy.
 func WithRetryPolicy(retryPolicy retryablehttp.CheckRetry) OptionClientFn {
 	return func(options *optionClientValue) {
@@ -270,12 +385,43 @@ func WithRetryLog(retryLog bool) OptionClientFn {
 	}
 }
 
+// WithRetryBackoff configures the backoff with the type and jitter factor in [0, 1], see NewBackoff.
+//
+// It replaces the backoff of WithBackoff.
+func WithRetryBackoff(backoffType BackoffType, jitter float64) OptionClientFn {
+	return func(options *optionClientValue) {
+		options.RetryBackoff = backoffType
+		options.RetryJitter = jitter
+	}
+}
+
+// WithRetryMethods limits the retry to the request methods, other methods are sent once.
+//
+//	klient.WithRetryMethods(http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete)
+func WithRetryMethods(methods ...string) OptionClientFn {
+	return func(options *optionClientValue) {
+		options.RetryMethods = methods
+	}
+}
+
 func WithRetryOptions(opts ...OptionRetryFn) OptionClientFn {
 	return func(options *optionClientValue) {
 		options.OptionRetryFns = append(options.OptionRetryFns, opts...)
 	}
 }
 
+// WithName sets the name of the client, KLIENT_<NAME>_* env values configure only this client
+// and override the global KLIENT_* values.
+//
+//	klient.New(klient.WithName("payments")) // KLIENT_PAYMENTS_BASE_URL, KLIENT_PAYMENTS_RETRY_MAX
+//
+// Names colliding with the global env names like "tls" or "retry" fail with ErrEnvName.
+func WithName(name string) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.Name = name
+	}
+}
+
 // WithDisableEnvValues configures the client to disable all env values check.
 //   - API_GATEWAY_ADDRESS and KLIENT_* env values will be disabled.
 func WithDisableEnvValues(disableEnvValues bool) OptionClientFn {
@@ -315,6 +461,73 @@ func WithTLSConfig(tlsConfig *TLSConfig) OptionClientFn {
 	}
 }
 
+// WithTLSReloadHook configures the metric hook of TLSConfig.ReloadInterval reloads.
+//
+// fn is called once for each failed reload with an error wrapping ErrTLSReload, count them for the metrics.
+// It is called while the new connection waits for the reload check, so it should not block.
+// Failures are also logged with the logger, the last good certificate and CA are used.
+//
+//	klient.WithTLSReloadHook(func(error) { tlsReloadFailures.Inc() })
+func WithTLSReloadHook(fn func(error)) OptionClientFn {
+	return func(options *optionClientValue) {
+		options.TLSReloadHook = fn
+	}
+}
+
+// WithCompression configures the client to compress request bodies equal or bigger than minSize bytes.
+//   - encoding is gzip or zstd, empty disables compression.
+//   - Body is compressed while sending, bodies with unknown length are read up to minSize to decide.
+//   - Retries compress the body again from GetBody, bodies without GetBody are replayed as set by WithBodyReplay.
+func WithCompression(encoding string, minSize int64) OptionClientFn {
+	return func(options *optionClientValue) {
+		options.CompressionEncoding = encoding
+		options.CompressionMinSize = minSize
+	}
+}
+
+// WithAcceptEncoding configures the accepted response encodings, default is zstd, br and gzip.
+//
+// Supported encodings are gzip, deflate, zstd and br.
+// The header is set on requests without Accept-Encoding or Range header.
+func WithAcceptEncoding(encodings ...string) OptionClientFn {
+	return func(options *optionClientValue) {
+		options.AcceptEncoding = encodings
+	}
+}
+
+// WithDisableDecompression configures the client to disable response decompression of zstd, br and deflate.
+//   - Go's http.Transport gzip handling is still active.
+func WithDisableDecompression(v bool) OptionClientFn {
+	return func(options *optionClientValue) {
+		options.DisableDecompression = v
+	}
+}
+
+// WithMaxResponseBodySize configures the maximum response body size in bytes, default is no limit.
+//   - Reading beyond the limit returns ErrResponseTooLarge.
+//   - 2xx responses with bigger Content-Length return ErrResponseTooLarge without reading.
+//   - Download and Events are not limited, set CtxWithMaxResponseBodySize on their context to limit them.
+//
+// Use CtxWithMaxResponseBodySize to override it per request.
+func WithMaxResponseBodySize(size int64) OptionClientFn {
+	return func(options *optionClientValue) {
+		options.MaxResponseBodySize = size
+	}
+}
+
+// WithBodyReplay configures the handling of request bodies which cannot be rewound for retries.
+//   - BodyReplayBuffer buffers up to memoryLimit in memory and the rest in a temp file.
+//   - BodyReplayDisableRetry sends the body as stream and disables retry of the request.
+//
+// Bodies with GetBody or io.Seeker are always rewound without buffering.
+// When a body cannot be replayed, request returns *BodyNotReplayableError wrapping ErrBodyNotReplayable.
+func WithBodyReplay(mode BodyReplayMode, memoryLimit int64) OptionClientFn {
+	return func(options *optionClientValue) {
+		options.BodyReplay = mode
+		options.BodyReplayMemoryLimit = memoryLimit
+	}
+}
+
 func WithBaseTransport(baseTransport http.RoundTripper) OptionClientFn {
 	return func(options *optionClientValue) {
 		options.BaseTransport = baseTransport
@@ -0,0 +1,505 @@
+package klient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrPageLimit       = errors.New("pagination max pages limit reached")
+	ErrPageCrossOrigin = errors.New("pagination next page has other origin")
+)
+
+// DefaultMaxPages is the default safety limit of the Paginator.
+var DefaultMaxPages = 10000
+
+// PageInfo is the information of the fetched page used to find the next page.
+type PageInfo struct {
+	// Request is the request of the page.
+	Request *http.Request
+	// Response of the page, body already read to Body.
+	Response *http.Response
+	// Body is the response body.
+	Body []byte
+	// Count is the number of items in the page.
+	Count int
+}
+
+// PageStrategy returns the URL of the next page, nil URL means the last page.
+type PageStrategy interface {
+	Next(page PageInfo) (*url.URL, error)
+}
+
+// PageStrategyFunc is a function adapter of PageStrategy.
+type PageStrategyFunc func(page PageInfo) (*url.URL, error)
+
+func (f PageStrategyFunc) Next(page PageInfo) (*url.URL, error) {
+	return f(page)
+}
+
+// Paginator iterates over pages of a paginated API.
+//
+//	p := klient.Paginator[User]{
+//		Client:   client.HTTP,
+//		Request:  req,
+//		Strategy: klient.PageLinkNext(),
+//	}
+//
+//	for user, err := range p.Items() {
+//		if err != nil {
+//			return err
+//		}
+//		// use user
+//	}
+type Paginator[T any] struct {
+	// Client is the http client to send requests.
+	Client *http.Client
+	// Request is the request of the first page.
+	Request *http.Request
+	// Strategy finds the next page.
+	Strategy PageStrategy
+	// Decode returns items of the page body.
+	// Default decodes the body as JSON array, see PageItemsJSON for nested arrays.
+	Decode func(body []byte) ([]T, error)
+	// MaxPages is the safety limit of the fetched pages, ErrPageLimit returned after the limit.
+	// Default is DefaultMaxPages, negative value disables the limit.
+	MaxPages int
+	// Prefetch fetches the next page while the current page is consumed.
+	Prefetch bool
+	// AllowCrossOrigin follows the next pages on other hosts, ErrPageCrossOrigin returned by default.
+	// Default headers and credentials of the client are sent to the other host.
+	AllowCrossOrigin bool
+}
+
+// pageFirst is implemented by the strategies changing the first request.
+type pageFirst interface {
+	first(u *url.URL) *url.URL
+}
+
+type pageResult[T any] struct {
+	items []T
+	err   error
+}
+
+// Items iterates over items of all pages.
+func (p *Paginator[T]) Items() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for items, err := range p.Pages() {
+			if err != nil {
+				var empty T
+				yield(empty, err)
+
+				return
+			}
+
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Pages iterates over pages, each page is the items of the page.
+func (p *Paginator[T]) Pages() iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		req := p.Request
+		if s, ok := p.Strategy.(pageFirst); ok && req != nil {
+			req = req.Clone(req.Context())
+			req.URL = s.first(req.URL)
+		}
+
+		if !p.Prefetch || req == nil {
+			for items, err := range p.fetchAll(req, func() bool { return true }) {
+				if !yield(items, err) {
+					return
+				}
+			}
+
+			return
+		}
+
+		ctx, cancel := context.WithCancel(req.Context())
+		results := make(chan pageResult[T])
+		finished := make(chan struct{})
+
+		// stop the request in flight and wait the prefetch to finish
+		defer func() {
+			cancel()
+			<-finished
+		}()
+
+		go func() {
+			defer close(finished)
+			defer close(results)
+
+			for items, err := range p.fetchAll(req.WithContext(ctx), func() bool { return ctx.Err() == nil }) {
+				select {
+				case results <- pageResult[T]{items: items, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+
+		for result := range results {
+			if !yield(result.items, result.err) {
+				return
+			}
+		}
+	}
+}
+
+// fetchAll fetches pages sequentially starting from req, stops when next returns false.
+func (p *Paginator[T]) fetchAll(req *http.Request, next func() bool) iter.Seq2[[]T, error] {
+	return func(yield func([]T, error) bool) {
+		maxPages := p.MaxPages
+		if maxPages == 0 {
+			maxPages = DefaultMaxPages
+		}
+
+		for page := 0; req != nil && next(); page++ {
+			if maxPages > 0 && page >= maxPages {
+				yield(nil, fmt.Errorf("%w: %d", ErrPageLimit, maxPages))
+
+				return
+			}
+
+			items, nextURL, err := p.fetch(req)
+			if err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			if nextURL == nil {
+				yield(items, nil)
+
+				return
+			}
+
+			if req, err = nextRequest(req, nextURL); err != nil {
+				yield(nil, err)
+
+				return
+			}
+
+			if !yield(items, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (p *Paginator[T]) fetch(req *http.Request) ([]T, *url.URL, error) {
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrRequest, err)
+	}
+
+	defer DrainBody(resp.Body)
+
+	if err := UnexpectedResponse(resp); err != nil {
+		return nil, nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read page body: %w", err)
+	}
+
+	decode := p.Decode
+	if decode == nil {
+		decode = PageItemsJSON[T]("")
+	}
+
+	items, err := decode(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nextURL, err := p.Strategy.Next(PageInfo{
+		Request:  req,
+		Response: resp,
+		Body:     body,
+		Count:    len(items),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("next page: %w", err)
+	}
+
+	if nextURL != nil && !p.AllowCrossOrigin && !sameOrigin(pageURL(req, resp), nextURL) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrPageCrossOrigin, nextURL.Redacted())
+	}
+
+	return items, nextURL, nil
+}
+
+// pageURL returns the absolute URL of the page, request URL is relative when resolved with the client's base URL.
+func pageURL(req *http.Request, resp *http.Response) *url.URL {
+	if req.URL.Host == "" && resp.Request != nil && resp.Request.URL != nil {
+		return resp.Request.URL
+	}
+
+	return req.URL
+}
+
+// sameOrigin reports the next URL is on the host of the page, relative URLs are on the same host.
+func sameOrigin(page, next *url.URL) bool {
+	if next.Host == "" {
+		return true
+	}
+
+	return strings.EqualFold(page.Host, next.Host) && (next.Scheme == "" || strings.EqualFold(page.Scheme, next.Scheme))
+}
+
+// nextRequest clones the previous request with the next page URL.
+func nextRequest(req *http.Request, u *url.URL) (*http.Request, error) {
+	next := req.Clone(req.Context())
+	next.URL = u
+	next.Host = ""
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrCreateRequest, err)
+		}
+
+		next.Body = body
+	}
+
+	return next, nil
+}
+
+// PageItemsJSON decodes items from the JSON array in the field of the body.
+//
+// Field is a dot separated path like "data.items", empty field means the body is the array.
+func PageItemsJSON[T any](field string) func(body []byte) ([]T, error) {
+	return func(body []byte) ([]T, error) {
+		raw, err := jsonField(body, field)
+		if err != nil {
+			return nil, err
+		}
+
+		var items []T
+		if len(raw) == 0 {
+			return items, nil
+		}
+
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("decode page items: %w", err)
+		}
+
+		return items, nil
+	}
+}
+
+// PageLinkNext follows the RFC 8288 Link header with rel="next".
+//
+// Relative links are resolved against the page URL, the request URL joined with the base URL of the client.
+func PageLinkNext() PageStrategy {
+	return PageStrategyFunc(func(page PageInfo) (*url.URL, error) {
+		for _, link := range page.Response.Header.Values("Link") {
+			target, ok := linkNext(link)
+			if !ok {
+				continue
+			}
+
+			u, err := url.Parse(target)
+			if err != nil {
+				return nil, fmt.Errorf("parse next link: %w", err)
+			}
+
+			base := pageURL(page.Request, page.Response)
+			if !base.IsAbs() {
+				// not sent through the client, resolved against the base URL later
+				return u, nil
+			}
+
+			return base.ResolveReference(u), nil
+		}
+
+		return nil, nil
+	})
+}
+
+// linkNext returns the target of rel="next" in the Link header value.
+func linkNext(header string) (string, bool) {
+	for _, link := range splitLinks(header) {
+		target, params, _ := strings.Cut(link, ";")
+		target = strings.TrimSpace(target)
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if !strings.EqualFold(strings.TrimSpace(key), "rel") {
+				continue
+			}
+
+			for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+				if strings.EqualFold(rel, "next") {
+					return target[1 : len(target)-1], true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// splitLinks splits the Link header value by commas outside of the URI references and quotes.
+func splitLinks(header string) []string {
+	var (
+		links  []string
+		quoted bool
+		inURI  bool
+		start  int
+	)
+
+	for i, c := range header {
+		switch {
+		case c == '<' && !quoted:
+			inURI = true
+		case c == '>' && !quoted:
+			inURI = false
+		case c == '"' && !inURI:
+			quoted = !quoted
+		case c == ',' && !quoted && !inURI:
+			links = append(links, header[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(links, header[start:])
+}
+
+// PageCursor sets the cursor to the query parameter for the next page.
+//
+// Extract returns the cursor of the next page from the page, empty cursor means the last page.
+func PageCursor(param string, extract func(page PageInfo) (string, error)) PageStrategy {
+	return PageStrategyFunc(func(page PageInfo) (*url.URL, error) {
+		cursor, err := extract(page)
+		if err != nil || cursor == "" {
+			return nil, err
+		}
+
+		return withQuery(page.Request.URL, map[string]string{param: cursor}), nil
+	})
+}
+
+// PageCursorJSON is PageCursor with extracting the cursor from the JSON body field.
+//
+// Field is a dot separated path like "meta.next_cursor".
+func PageCursorJSON(param, field string) PageStrategy {
+	return PageCursor(param, func(page PageInfo) (string, error) {
+		raw, err := jsonField(page.Body, field)
+		if err != nil || len(raw) == 0 {
+			return "", err
+		}
+
+		var v any
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return "", fmt.Errorf("decode cursor: %w", err)
+		}
+
+		switch v := v.(type) {
+		case nil:
+			return "", nil
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		}
+
+		return "", fmt.Errorf("decode cursor: unexpected type %T", v)
+	})
+}
+
+// PageOffset increments the offset query parameter with the item count of the page.
+//
+// Last page is the page with less items than limit.
+// Limit parameter is set to all requests, also the first one, when limitParam is not empty.
+func PageOffset(offsetParam, limitParam string, limit int) PageStrategy {
+	return pageOffset{offsetParam: offsetParam, limitParam: limitParam, limit: limit}
+}
+
+type pageOffset struct {
+	offsetParam string
+	limitParam  string
+	limit       int
+}
+
+func (s pageOffset) first(u *url.URL) *url.URL {
+	if s.limitParam == "" {
+		return u
+	}
+
+	return withQuery(u, map[string]string{s.limitParam: strconv.Itoa(s.limit)})
+}
+
+func (s pageOffset) Next(page PageInfo) (*url.URL, error) {
+	if page.Count == 0 || page.Count < s.limit {
+		return nil, nil
+	}
+
+	offset := 0
+	if v := page.Request.URL.Query().Get(s.offsetParam); v != "" {
+		var err error
+		if offset, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("parse offset: %w", err)
+		}
+	}
+
+	params := map[string]string{s.offsetParam: strconv.Itoa(offset + page.Count)}
+	if s.limitParam != "" {
+		params[s.limitParam] = strconv.Itoa(s.limit)
+	}
+
+	return withQuery(page.Request.URL, params), nil
+}
+
+func withQuery(u *url.URL, params map[string]string) *url.URL {
+	next := *u
+	query := next.Query()
+	for k, v := range params {
+		query.Set(k, v)
+	}
+
+	next.RawQuery = query.Encode()
+
+	return &next
+}
+
+// jsonField returns the raw value of dot separated field, nil if not exist.
+func jsonField(body []byte, field string) (json.RawMessage, error) {
+	raw := json.RawMessage(bytes.TrimSpace(body))
+	if field == "" {
+		return raw, nil
+	}
+
+	for _, key := range strings.Split(field, ".") {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("decode field %q: %w", field, err)
+		}
+
+		var ok bool
+		if raw, ok = m[key]; !ok {
+			return nil, nil
+		}
+	}
+
+	return raw, nil
+}
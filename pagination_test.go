@@ -0,0 +1,221 @@
+package klient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestPaginator(t *testing.T) {
+	const total = 7
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		switch r.URL.Path {
+		case "/api/link", "/api/query":
+			page, _ := strconv.Atoi(query.Get("page"))
+			items := []int{}
+			for i := page * 3; i < min(page*3+3, total); i++ {
+				items = append(items, i)
+			}
+
+			if (page+1)*3 < total {
+				next := fmt.Sprintf("link?page=%d", page+1)
+				switch {
+				case r.URL.Path == "/api/query":
+					// query only reference of the page URL
+					next = fmt.Sprintf("?page=%d", page+1)
+				case page == 1:
+					next = "http://" + r.Host + "/api/" + next
+				}
+
+				w.Header().Set("Link", fmt.Sprintf(`<https://example.com/prev>; rel="prev", <%s>; rel="next"`, next))
+			}
+
+			_ = json.NewEncoder(w).Encode(items)
+		case "/api/other":
+			w.Header().Set("Link", `<https://example.com/api/other?page=1>; rel="next"`)
+			_ = json.NewEncoder(w).Encode([]int{0})
+		case "/api/cursor":
+			start, _ := strconv.Atoi(query.Get("cursor"))
+			items := []int{}
+			for i := start; i < min(start+3, total); i++ {
+				items = append(items, i)
+			}
+
+			next := ""
+			if start+3 < total {
+				next = strconv.Itoa(start + 3)
+			}
+
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{"items": items},
+				"meta": map[string]any{"next": next},
+			})
+		case "/api/offset":
+			offset, _ := strconv.Atoi(query.Get("offset"))
+			limit, _ := strconv.Atoi(query.Get("limit"))
+			if limit == 0 {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			items := []int{}
+			for i := offset; i < min(offset+limit, total); i++ {
+				items = append(items, i)
+			}
+
+			_ = json.NewEncoder(w).Encode(items)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer httpServer.Close()
+
+	client, err := New(WithBaseURL(httpServer.URL + "/api/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		strategy PageStrategy
+		decode   func([]byte) ([]int, error)
+		prefetch bool
+	}{
+		{name: "link", path: "link", strategy: PageLinkNext()},
+		{name: "link prefetch", path: "link", strategy: PageLinkNext(), prefetch: true},
+		{name: "link query", path: "query", strategy: PageLinkNext()},
+		{name: "cursor", path: "cursor", strategy: PageCursorJSON("cursor", "meta.next"), decode: PageItemsJSON[int]("data.items")},
+		{name: "offset", path: "offset", strategy: PageOffset("offset", "limit", 3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p := Paginator[int]{
+				Client:   client.HTTP,
+				Request:  req,
+				Strategy: tt.strategy,
+				Decode:   tt.decode,
+				Prefetch: tt.prefetch,
+			}
+
+			var got []int
+			for v, err := range p.Items() {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got = append(got, v)
+			}
+
+			if fmt.Sprint(got) != "[0 1 2 3 4 5 6]" {
+				t.Fatalf("unexpected items %v", got)
+			}
+		})
+	}
+
+	t.Run("cross origin", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "other", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p := Paginator[int]{
+			Client:   client.HTTP,
+			Request:  req,
+			Strategy: PageLinkNext(),
+		}
+
+		for _, err := range p.Pages() {
+			if !errors.Is(err, ErrPageCrossOrigin) {
+				t.Fatalf("expected ErrPageCrossOrigin, got %v", err)
+			}
+		}
+	})
+
+	t.Run("prefetch stop", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "link", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			requests atomic.Int32
+			canceled atomic.Bool
+			started  = make(chan struct{})
+		)
+
+		p := Paginator[int]{
+			Client: &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if requests.Add(1) > 1 {
+					// second page waits until the iteration stops
+					close(started)
+					<-r.Context().Done()
+					canceled.Store(true)
+
+					return nil, r.Context().Err()
+				}
+
+				return client.HTTP.Transport.RoundTrip(r)
+			})},
+			Request:  req,
+			Strategy: PageLinkNext(),
+			Prefetch: true,
+		}
+
+		for range p.Pages() {
+			<-started
+
+			break
+		}
+
+		if !canceled.Load() {
+			t.Fatal("expected prefetch to be canceled and finished")
+		}
+	})
+
+	t.Run("max pages", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "link", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p := Paginator[int]{
+			Client:   client.HTTP,
+			Request:  req,
+			Strategy: PageLinkNext(),
+			MaxPages: 2,
+		}
+
+		var pages int
+		for _, err := range p.Pages() {
+			if err != nil {
+				if !errors.Is(err, ErrPageLimit) {
+					t.Fatalf("expected ErrPageLimit, got %v", err)
+				}
+
+				break
+			}
+
+			pages++
+		}
+
+		if pages != 2 {
+			t.Fatalf("expected 2 pages, got %d", pages)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package klient
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var ErrPinMismatch = errors.New("certificate pin mismatch")
+
+// PinError is returned when no certificate of the server matches the pins.
+//
+// Ordinary certificate verification failures are *tls.CertificateVerificationError.
+type PinError struct {
+	// ServerName is the verified server name.
+	ServerName string
+	// Pins are the SPKI pins of the verified certificate chains, only the leaf when verification is skipped.
+	Pins []string
+}
+
+func (e *PinError) Error() string {
+	return fmt.Sprintf("%v: server %q with pins %s", ErrPinMismatch, e.ServerName, strings.Join(e.Pins, ", "))
+}
+
+func (e *PinError) Unwrap() error {
+	return ErrPinMismatch
+}
+
+// SPKIPin returns the base64 encoded SHA-256 hash of the certificate's subject public key info.
+//
+// Same as the output of:
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// parsePin returns the pin without the optional "sha256/" prefix, it must be a base64 SHA-256 hash.
+func parsePin(pin string) (string, error) {
+	pin = strings.TrimPrefix(pin, "sha256/")
+
+	sum, err := base64.StdEncoding.DecodeString(pin)
+	if err != nil || len(sum) != sha256.Size {
+		return "", fmt.Errorf("invalid pin %q, must be base64 of a sha256 hash", pin)
+	}
+
+	return pin, nil
+}
+
+// pinCheck checks the pins with the verified certificate chains of the server.
+type pinCheck func(cs tls.ConnectionState, chains [][]*x509.Certificate) error
+
+// pinVerifier returns the check of the pins, nil without pins.
+//
+// onReport is called with the *PinError in report only mode instead of failing the connection.
+func (t TLSConfig) pinVerifier(onReport func(error)) (pinCheck, error) {
+	if len(t.Pins) == 0 {
+		return nil, nil
+	}
+
+	pins := make([]string, 0, len(t.Pins)+len(t.BackupPins))
+	for _, pin := range slices.Concat(t.Pins, t.BackupPins) {
+		pin, err := parsePin(pin)
+		if err != nil {
+			return nil, err
+		}
+
+		pins = append(pins, pin)
+	}
+
+	reportOnly := t.PinsReportOnly
+
+	return func(cs tls.ConnectionState, chains [][]*x509.Certificate) error {
+		// only verified chains, other certificates sent by the server prove nothing
+		var serverPins []string
+		for _, chain := range chains {
+			for _, cert := range chain {
+				pin := SPKIPin(cert)
+				if slices.Contains(pins, pin) {
+					return nil
+				}
+
+				if !slices.Contains(serverPins, pin) {
+					serverPins = append(serverPins, pin)
+				}
+			}
+		}
+
+		err := &PinError{ServerName: cs.ServerName, Pins: serverPins}
+		if reportOnly {
+			if onReport != nil {
+				onReport(err)
+			}
+
+			return nil
+		}
+
+		return err
+	}, nil
+}
+
+// verifyConnectionPins returns the VerifyConnection of the pins with the chains verified by the handshake.
+//
+// When the verification is skipped only the leaf certificate is matched.
+func verifyConnectionPins(verifyPins pinCheck) func(tls.ConnectionState) error {
+	if verifyPins == nil {
+		return nil
+	}
+
+	return func(cs tls.ConnectionState) error {
+		chains := cs.VerifiedChains
+		if len(chains) == 0 && len(cs.PeerCertificates) > 0 {
+			chains = [][]*x509.Certificate{cs.PeerCertificates[:1]}
+		}
+
+		return verifyPins(cs, chains)
+	}
+}
@@ -2,10 +2,11 @@ package klient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-
 	"slices"
+	"strings"
 
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/worldline-go/logz"
@@ -126,6 +127,12 @@ func retryPolicyOpts(ctx context.Context, resp *http.Response, err error, retryV
 		}
 	}
 
+	// pins do not change between attempts
+	var pinErr *PinError
+	if errors.As(err, &pinErr) {
+		return false, err
+	}
+
 	if err != nil && ctx.Err() == nil && isTimeoutError(err) {
 		return true, err
 	}
@@ -156,6 +163,22 @@ func retryError(retry bool, err error, resp *http.Response, log logz.Adapter, er
 	return retry, fmt.Errorf("%w: [%s]", err, response)
 }
 
+// retryMethodTransport disables retry of the requests with other methods.
+type retryMethodTransport struct {
+	base    http.RoundTripper
+	methods []string
+}
+
+var _ http.RoundTripper = (*retryMethodTransport)(nil)
+
+func (t *retryMethodTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if slices.ContainsFunc(t.methods, func(method string) bool { return strings.EqualFold(method, req.Method) }) {
+		return t.base.RoundTrip(req)
+	}
+
+	return t.base.RoundTrip(req.WithContext(ctxWithRetryDisabled(req.Context())))
+}
+
 func PassthroughErrorHandler(resp *http.Response, err error, _ int) (*http.Response, error) {
 	if resp == nil {
 		return nil, err
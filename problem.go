@@ -0,0 +1,96 @@
+package klient
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+// MediaTypeProblemJSON is the media type of RFC 7807 problem details.
+const MediaTypeProblemJSON = "application/problem+json"
+
+// ProblemDetails is the RFC 7807 problem details object.
+//
+// Unknown members are collected in Extensions.
+type ProblemDetails struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	Extensions map[string]any `json:"-"`
+}
+
+var problemDetailsMembers = []string{"type", "title", "status", "detail", "instance"}
+
+func (p *ProblemDetails) Error() string {
+	msg := p.Title
+	if msg == "" {
+		msg = p.Type
+	}
+
+	if p.Detail != "" {
+		if msg == "" {
+			return p.Detail
+		}
+
+		return fmt.Sprintf("%s: %s", msg, p.Detail)
+	}
+
+	return msg
+}
+
+func (p *ProblemDetails) UnmarshalJSON(data []byte) error {
+	type problem ProblemDetails
+
+	var v problem
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var all map[string]any
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+
+	for _, member := range problemDetailsMembers {
+		delete(all, member)
+	}
+
+	if len(all) > 0 {
+		v.Extensions = all
+	}
+
+	*p = ProblemDetails(v)
+
+	return nil
+}
+
+func (p ProblemDetails) MarshalJSON() ([]byte, error) {
+	type problem ProblemDetails
+
+	data, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return data, err
+	}
+
+	all := make(map[string]any, len(p.Extensions)+len(problemDetailsMembers))
+	for k, v := range p.Extensions {
+		all[k] = v
+	}
+
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(all)
+}
+
+// IsProblemJSON reports whether the response content type is application/problem+json.
+func IsProblemJSON(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	return mediaType == MediaTypeProblemJSON
+}
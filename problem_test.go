@@ -0,0 +1,130 @@
+package klient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestResponseJSON(t *testing.T) {
+	newResponse := func(code int, contentType, body string) *http.Response {
+		return &http.Response{
+			StatusCode:    code,
+			Header:        http.Header{"Content-Type": []string{contentType}, "X-Request-Id": []string{"abc"}},
+			Body:          io.NopCloser(strings.NewReader(body)),
+			ContentLength: int64(len(body)),
+		}
+	}
+
+	t.Run("success", func(t *testing.T) {
+		type value struct {
+			Name string `json:"name"`
+		}
+
+		v, err := ResponseJSON[value, ProblemDetails](newResponse(http.StatusOK, "application/json", `{"name":"test"}`))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if v.Name != "test" {
+			t.Fatalf("expected test, got %s", v.Name)
+		}
+	})
+
+	t.Run("problem details", func(t *testing.T) {
+		body := `{"type":"https://example.com/probs/out-of-credit","title":"You do not have enough credit.","status":403,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","balance":30}`
+
+		_, err := ResponseJSON[map[string]any, ProblemDetails](newResponse(http.StatusForbidden, MediaTypeProblemJSON, body))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		var problem *ProblemDetails
+		if !errors.As(err, &problem) {
+			t.Fatalf("expected problem details, got %T", err)
+		}
+
+		want := &ProblemDetails{
+			Type:       "https://example.com/probs/out-of-credit",
+			Title:      "You do not have enough credit.",
+			Status:     403,
+			Detail:     "Your current balance is 30, but that costs 50.",
+			Instance:   "/account/12345/msgs/abc",
+			Extensions: map[string]any{"balance": float64(30)},
+		}
+		if diff := deep.Equal(problem, want); diff != nil {
+			t.Fatalf("problem diff = %v", diff)
+		}
+
+		var respErr *ResponseErrorOf[ProblemDetails]
+		if !errors.As(err, &respErr) {
+			t.Fatalf("expected response error, got %T", err)
+		}
+
+		if respErr.StatusCode != http.StatusForbidden || respErr.RequestID != "abc" {
+			t.Fatalf("unexpected response error %+v", respErr)
+		}
+	})
+
+	t.Run("custom error", func(t *testing.T) {
+		type apiError struct {
+			Code string `json:"code"`
+		}
+
+		_, err := ResponseJSON[struct{}, apiError](newResponse(http.StatusBadRequest, "application/json", `{"code":"E42"}`))
+
+		var respErr *ResponseErrorOf[apiError]
+		if !errors.As(err, &respErr) {
+			t.Fatalf("expected response error, got %T", err)
+		}
+
+		if respErr.Err.Code != "E42" {
+			t.Fatalf("expected E42, got %s", respErr.Err.Code)
+		}
+	})
+
+	t.Run("custom error with problem details", func(t *testing.T) {
+		type apiError struct {
+			Code string `json:"code"`
+		}
+
+		_, err := ResponseJSON[struct{}, apiError](newResponse(http.StatusNotFound, MediaTypeProblemJSON, `{"title":"Not found","code":"E404"}`))
+
+		var respErr *ResponseErrorOf[apiError]
+		if !errors.As(err, &respErr) {
+			t.Fatalf("expected response error, got %T", err)
+		}
+
+		if respErr.Err.Code != "E404" || respErr.Problem == nil || respErr.Problem.Title != "Not found" {
+			t.Fatalf("unexpected response error %+v", respErr)
+		}
+	})
+
+	t.Run("empty problem details", func(t *testing.T) {
+		_, err := ResponseJSON[struct{}, ProblemDetails](newResponse(http.StatusInternalServerError, MediaTypeProblemJSON, `{}`))
+		if err == nil {
+			t.Fatal("expected error")
+		}
+
+		if want := "unexpected response [500] with request id [abc]: {}"; err.Error() != want {
+			t.Fatalf("expected %q, got %q", want, err.Error())
+		}
+	})
+
+	t.Run("not decodable", func(t *testing.T) {
+		_, err := ResponseJSON[struct{}, ProblemDetails](newResponse(http.StatusBadGateway, "text/plain", `bad gateway`))
+
+		var respErr *ResponseError
+		if !errors.As(err, &respErr) {
+			t.Fatalf("expected response error, got %T", err)
+		}
+
+		if respErr.Body != "bad gateway" {
+			t.Fatalf("expected body, got %s", respErr.Body)
+		}
+	})
+}
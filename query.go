@@ -0,0 +1,220 @@
+package klient
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrQueryType = errors.New("unsupported query type")
+
+var (
+	typeTime          = reflect.TypeFor[time.Time]()
+	typeTextMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// EncodeQuery encodes v to url.Values.
+//
+// v could be url.Values, map[string]string, map[string][]string or a struct with `query` tags.
+//
+//	type ListUsers struct {
+//		Name   string    `query:"name,omitempty"`
+//		IDs    []int     `query:"id"`
+//		Since  time.Time `query:"since,omitempty"`      // RFC3339
+//		Before time.Time `query:"before,unix"`          // unix seconds
+//		Active *bool     `query:"active,omitempty"`
+//		Skip   string    `query:"-"`
+//	}
+//
+// Slices and arrays are encoded as repeated keys, time.Time with RFC3339 or
+// unix/unixmilli options, encoding.TextMarshaler and fmt.Stringer with their text value.
+// Fields without tag use the field name and embedded structs are flattened.
+func EncodeQuery(v any) (url.Values, error) {
+	values := url.Values{}
+
+	switch v := v.(type) {
+	case nil:
+		return values, nil
+	case url.Values:
+		for k, vs := range v {
+			values[k] = append(values[k], vs...)
+		}
+
+		return values, nil
+	case map[string][]string:
+		for k, vs := range v {
+			values[k] = append(values[k], vs...)
+		}
+
+		return values, nil
+	case map[string]string:
+		for k, vv := range v {
+			values.Add(k, vv)
+		}
+
+		return values, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return values, nil
+		}
+
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T", ErrQueryType, v)
+	}
+
+	if err := encodeQueryStruct(values, rv); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func encodeQueryStruct(values url.Values, rv reflect.Value) error {
+	rt := rv.Type()
+
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get("query")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		options := strings.Split(opts, ",")
+
+		fv := rv.Field(i)
+
+		if field.Anonymous && name == "" {
+			for fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					break
+				}
+
+				fv = fv.Elem()
+			}
+
+			if fv.Kind() == reflect.Struct && fv.Type() != typeTime {
+				if err := encodeQueryStruct(values, fv); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if slices.Contains(options, "omitempty") && fv.IsZero() {
+			continue
+		}
+
+		if err := encodeQueryValue(values, name, fv, options); err != nil {
+			return fmt.Errorf("query field %s: %w", field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func encodeQueryValue(values url.Values, name string, fv reflect.Value, options []string) error {
+	for fv.Kind() == reflect.Pointer || fv.Kind() == reflect.Interface {
+		if fv.IsNil() {
+			return nil
+		}
+
+		fv = fv.Elem()
+	}
+
+	if fv.Type() != typeTime && !fv.Type().Implements(typeTextMarshaler) {
+		switch fv.Kind() {
+		case reflect.Slice, reflect.Array:
+			if fv.Type().Elem().Kind() == reflect.Uint8 {
+				break
+			}
+
+			for i := range fv.Len() {
+				if err := encodeQueryValue(values, name, fv.Index(i), options); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+	}
+
+	v, err := queryString(fv, options)
+	if err != nil {
+		return err
+	}
+
+	values.Add(name, v)
+
+	return nil
+}
+
+func queryString(fv reflect.Value, options []string) (string, error) {
+	if fv.Type() == typeTime {
+		t := fv.Interface().(time.Time)
+
+		switch {
+		case slices.Contains(options, "unix"):
+			return strconv.FormatInt(t.Unix(), 10), nil
+		case slices.Contains(options, "unixmilli"):
+			return strconv.FormatInt(t.UnixMilli(), 10), nil
+		}
+
+		return t.Format(time.RFC3339), nil
+	}
+
+	if m, ok := fv.Interface().(encoding.TextMarshaler); ok {
+		v, err := m.MarshalText()
+
+		return string(v), err
+	}
+
+	if s, ok := fv.Interface().(fmt.Stringer); ok {
+		return s.String(), nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(fv.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'f', -1, 64), nil
+	case reflect.Slice:
+		// []byte
+		return string(fv.Bytes()), nil
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrQueryType, fv.Type())
+}
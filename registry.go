@@ -0,0 +1,340 @@
+package klient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+var (
+	ErrClientNotFound = errors.New("client not found")
+	ErrRegistryClosed = errors.New("registry closed")
+)
+
+// Registry holds named clients created from their configurations.
+//
+// Each client is created with WithName, so KLIENT_<NAME>_* env values are applied to it.
+// Clients with the same pool, timeout, proxy, TLS and http2 settings share one connection pool.
+// TLS reload failures and pin reports of a shared pool are reported with the logger and
+// WithTLSReloadHook of the client which created it first, use OptionRegistry.WithDisableSharedTransport
+// for separate reports.
+type Registry struct {
+	configs       map[string]Config
+	options       []OptionClientFn
+	disableShared bool
+
+	mutex      sync.Mutex
+	clients    map[string]*Client
+	pools      map[string]*http.Transport
+	transports []*http.Transport
+	closed     bool
+}
+
+type optionRegistryValue struct {
+	Defaults               Config
+	Lazy                   bool
+	ClientOptions          []OptionClientFn
+	DisableSharedTransport bool
+}
+
+// OptionRegistryFn is a function that configures the registry.
+type OptionRegistryFn func(*optionRegistryValue)
+
+type OptionRegistryHolder struct{}
+
+var OptionRegistry = OptionRegistryHolder{}
+
+// WithDefaults sets the configuration merged under each client configuration, set values of the client win.
+func (OptionRegistryHolder) WithDefaults(defaults Config) OptionRegistryFn {
+	return func(o *optionRegistryValue) {
+		o.Defaults = defaults
+	}
+}
+
+// WithLazy creates the clients on the first Get instead of NewRegistry.
+//
+// Configurations are still validated in NewRegistry.
+func (OptionRegistryHolder) WithLazy(lazy bool) OptionRegistryFn {
+	return func(o *optionRegistryValue) {
+		o.Lazy = lazy
+	}
+}
+
+// WithClientOptions adds options to all clients, configuration values override them.
+func (OptionRegistryHolder) WithClientOptions(opts ...OptionClientFn) OptionRegistryFn {
+	return func(o *optionRegistryValue) {
+		o.ClientOptions = append(o.ClientOptions, opts...)
+	}
+}
+
+// WithDisableSharedTransport gives each client its own connection pool.
+func (OptionRegistryHolder) WithDisableSharedTransport(disable bool) OptionRegistryFn {
+	return func(o *optionRegistryValue) {
+		o.DisableSharedTransport = disable
+	}
+}
+
+// NewRegistry creates a registry of the named configurations.
+//
+// All clients are created unless OptionRegistry.WithLazy is set, errors of all clients are returned joined.
+func NewRegistry(configs map[string]Config, opts ...OptionRegistryFn) (*Registry, error) {
+	o := optionRegistryValue{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := &Registry{
+		configs:       make(map[string]Config, len(configs)),
+		options:       o.ClientOptions,
+		disableShared: o.DisableSharedTransport,
+		clients:       make(map[string]*Client, len(configs)),
+		pools:         make(map[string]*http.Transport),
+	}
+
+	for name, cfg := range configs {
+		r.configs[name] = mergeConfig(cfg, o.Defaults)
+	}
+
+	var errs []error
+	for _, name := range r.Names() {
+		if o.Lazy {
+			cfg := r.configs[name]
+			if err := cfg.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("client %q: %w: %w", name, ErrInvalidConfig, err))
+			}
+
+			continue
+		}
+
+		if _, err := r.Get(name); err != nil {
+			errs = append(errs, fmt.Errorf("client %q: %w", name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		r.Close()
+
+		return nil, errors.Join(errs...)
+	}
+
+	return r, nil
+}
+
+// Names returns the sorted names of the clients.
+func (r *Registry) Names() []string {
+	return slices.Sorted(maps.Keys(r.configs))
+}
+
+// Get returns the client of the name, creates it on the first call.
+//
+// Failed creation is not cached, the next call tries again.
+func (r *Registry) Get(name string) (*Client, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		return nil, ErrRegistryClosed
+	}
+
+	if client, ok := r.clients[name]; ok {
+		return client, nil
+	}
+
+	cfg, ok := r.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrClientNotFound, name)
+	}
+
+	opts := []OptionClientFn{WithName(name)}
+
+	var (
+		key       string
+		transport *http.Transport
+		pooled    bool
+	)
+
+	if o, ok := r.transportOption(name, cfg); ok {
+		if !r.disableShared {
+			key = transportKey(&o)
+			transport, pooled = r.pools[key]
+		}
+
+		if pooled {
+			opts = append(opts, withSharedTransport(transport))
+		} else {
+			transport = newBaseTransport(o.PooledClient)
+			opts = append(opts, withOwnedTransport(transport))
+		}
+	}
+
+	client, err := cfg.New(append(opts, r.options...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if transport != nil && !pooled {
+		r.transports = append(r.transports, transport)
+
+		if key != "" {
+			r.pools[key] = transport
+		}
+	}
+
+	r.clients[name] = client
+
+	return client, nil
+}
+
+// Close closes the idle connections of all clients, Get returns ErrRegistryClosed after it.
+//
+// In flight requests are not canceled.
+func (r *Registry) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.closed = true
+
+	for _, transport := range r.transports {
+		transport.CloseIdleConnections()
+	}
+
+	for _, client := range r.clients {
+		client.HTTP.CloseIdleConnections()
+	}
+}
+
+// transportOption returns the option values of the client with env values to compare the transport settings.
+//
+// False is returned when the registry should not set the transport, like a custom http client.
+func (r *Registry) transportOption(name string, cfg Config) (optionClientValue, bool) {
+	o := newOptionClientValue(append(slices.Clone(r.options), cfg.ToOption()))
+
+	if !DisableEnvValues && !o.DisableEnvValues {
+		envConfig, err := ConfigFromEnv(name)
+		if err != nil {
+			// New returns the error
+			return o, false
+		}
+
+		envConfig.BaseURL = ""
+
+		o = newOptionClientValue(append(append([]OptionClientFn{envConfig.ToOption()}, r.options...), cfg.ToOption()))
+
+		if envConfig.InsecureSkipVerify != nil && *envConfig.InsecureSkipVerify {
+			o.InsecureSkipVerify = true
+		}
+	}
+
+	return o, o.HTTPClient == nil && o.BaseTransport == nil
+}
+
+// transportKey is equal for the options configuring the base transport the same.
+//
+// Key is a hash to not keep the TLS keys and passwords in the registry.
+func transportKey(o *optionClientValue) string {
+	key, _ := json.Marshal(struct {
+		PooledClient          bool
+		HTTP2                 bool
+		Proxy                 string
+		InsecureSkipVerify    bool
+		TLSConfig             *TLSConfig
+		MaxConnections        int
+		MaxConnsPerHost       int
+		DialTimeout           time.Duration
+		KeepAlive             time.Duration
+		IdleConnTimeout       time.Duration
+		TLSHandshakeTimeout   time.Duration
+		ResponseHeaderTimeout time.Duration
+		ExpectContinueTimeout time.Duration
+	}{
+		PooledClient:          o.PooledClient,
+		HTTP2:                 o.HTTP2,
+		Proxy:                 o.Proxy,
+		InsecureSkipVerify:    o.InsecureSkipVerify,
+		TLSConfig:             o.TLSConfig,
+		MaxConnections:        o.MaxConnections,
+		MaxConnsPerHost:       o.MaxConnsPerHost,
+		DialTimeout:           o.DialTimeout,
+		KeepAlive:             o.KeepAlive,
+		IdleConnTimeout:       o.IdleConnTimeout,
+		TLSHandshakeTimeout:   o.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: o.ResponseHeaderTimeout,
+		ExpectContinueTimeout: o.ExpectContinueTimeout,
+	})
+
+	sum := sha256.Sum256(key)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func newBaseTransport(pooled bool) *http.Transport {
+	if pooled {
+		return cleanhttp.DefaultPooledTransport()
+	}
+
+	return cleanhttp.DefaultTransport()
+}
+
+// withOwnedTransport sets the base transport created by the registry, configured like the one of New.
+func withOwnedTransport(transport *http.Transport) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.BaseTransport = transport
+		o.ownedTransport = transport
+	}
+}
+
+// withSharedTransport sets the base transport configured by an other client.
+func withSharedTransport(transport *http.Transport) OptionClientFn {
+	return func(o *optionClientValue) {
+		o.BaseTransport = transport
+		o.sharedTransport = transport
+	}
+}
+
+// mergeConfig returns the config with the zero values filled from the defaults.
+//
+// Nested configs like tls and retry are merged field by field, maps like header key by key.
+// Slices like retry status codes are not merged, set slices replace the defaults.
+func mergeConfig(cfg, defaults Config) Config {
+	mergeValue(reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(defaults))
+
+	return cfg
+}
+
+func mergeValue(dst, src reflect.Value) {
+	for i := range dst.NumField() {
+		field, def := dst.Field(i), src.Field(i)
+
+		switch {
+		case def.IsZero():
+		case field.IsZero():
+			field.Set(def)
+		case field.Kind() == reflect.Pointer && field.Elem().Kind() == reflect.Struct:
+			// copy to not change the given config
+			merged := reflect.New(field.Elem().Type())
+			merged.Elem().Set(field.Elem())
+			mergeValue(merged.Elem(), def.Elem())
+			field.Set(merged)
+		case field.Kind() == reflect.Map:
+			merged := reflect.MakeMapWithSize(field.Type(), def.Len()+field.Len())
+			for _, m := range []reflect.Value{def, field} {
+				iter := m.MapRange()
+				for iter.Next() {
+					merged.SetMapIndex(iter.Key(), iter.Value())
+				}
+			}
+
+			field.Set(merged)
+		}
+	}
+}
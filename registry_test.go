@@ -0,0 +1,130 @@
+package klient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("X-Client")))
+	}))
+	defer server.Close()
+
+	disableRetry := true
+	registry, err := NewRegistry(map[string]Config{
+		"users":   {Header: map[string][]string{"X-Client": {"users"}}},
+		"orders":  {Header: map[string][]string{"X-Client": {"orders"}}},
+		"billing": {Header: map[string][]string{"X-Client": {"billing"}}, Transport: &TransportConfig{MaxConnsPerHost: 2}},
+	},
+		OptionRegistry.WithDefaults(Config{BaseURL: server.URL, DisableRetry: &disableRetry}),
+		OptionRegistry.WithClientOptions(WithDisableEnvValues(true)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range registry.Names() {
+		client, err := registry.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+
+		var body string
+		if err := client.Do(req, func(r *http.Response) error {
+			data, err := io.ReadAll(r.Body)
+			body = string(data)
+
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if body != name {
+			t.Errorf("expected response of %s, got %q", name, body)
+		}
+	}
+
+	if len(registry.transports) != 2 {
+		t.Errorf("expected 2 connection pools, got %d", len(registry.transports))
+	}
+
+	if _, err := registry.Get("unknown"); !errors.Is(err, ErrClientNotFound) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+
+	registry.Close()
+
+	if _, err := registry.Get("users"); !errors.Is(err, ErrRegistryClosed) {
+		t.Errorf("expected closed error, got %v", err)
+	}
+}
+
+func TestRegistryLazy(t *testing.T) {
+	configs := map[string]Config{
+		"users": {BaseURL: "http://users"},
+		"bad":   {BaseURL: "http://bad", RetryMax: -1},
+	}
+
+	_, err := NewRegistry(configs, OptionRegistry.WithLazy(true))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected invalid config error, got %v", err)
+	}
+
+	delete(configs, "bad")
+
+	registry, err := NewRegistry(configs, OptionRegistry.WithLazy(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer registry.Close()
+
+	if len(registry.clients) != 0 {
+		t.Fatalf("expected no created client, got %d", len(registry.clients))
+	}
+
+	first, err := registry.Get("users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if second, _ := registry.Get("users"); first != second {
+		t.Error("expected the same client")
+	}
+}
+
+func TestMergeConfig(t *testing.T) {
+	enabled := true
+	cfg := mergeConfig(
+		Config{RetryMax: 2, Transport: &TransportConfig{DialTimeout: time.Second}},
+		Config{RetryMax: 5, Timeout: time.Minute, HTTP2: &enabled, Transport: &TransportConfig{DialTimeout: time.Minute, MaxConnections: 10}},
+	)
+
+	if cfg.RetryMax != 2 || cfg.Timeout != time.Minute || cfg.HTTP2 == nil || !*cfg.HTTP2 {
+		t.Errorf("unexpected merged config %+v", cfg)
+	}
+
+	if cfg.Transport.DialTimeout != time.Second || cfg.Transport.MaxConnections != 10 {
+		t.Errorf("unexpected merged transport %+v", cfg.Transport)
+	}
+
+	header := map[string][]string{"X-Client": {"client"}}
+	cfg = mergeConfig(
+		Config{Header: header},
+		Config{Header: map[string][]string{"X-Client": {"default"}, "X-Default": {"default"}}},
+	)
+
+	if len(cfg.Header) != 2 || cfg.Header["X-Client"][0] != "client" || cfg.Header["X-Default"][0] != "default" {
+		t.Errorf("unexpected merged header %v", cfg.Header)
+	}
+
+	if len(header) != 1 {
+		t.Errorf("given header changed %v", header)
+	}
+}
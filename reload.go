@@ -0,0 +1,135 @@
+package klient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+var ErrReload = errors.New("reload failed")
+
+// clientState is the transport chain of the client, replaced by Reload.
+type clientState struct {
+	transport http.RoundTripper
+	// stream is used for long living connections like server-sent events.
+	stream streamValue
+	// base is the transport owned by the state, nil for shared and not *http.Transport ones.
+	base *http.Transport
+
+	active  atomic.Int64
+	retired atomic.Bool
+}
+
+func (s *clientState) closeIdle() {
+	if s.base != nil {
+		s.base.CloseIdleConnections()
+	}
+}
+
+// done is called when a request of the state is finished.
+func (s *clientState) done() {
+	if s.active.Add(-1) == 0 && s.retired.Load() {
+		s.closeIdle()
+	}
+}
+
+// retire closes the idle connections, the rest closed after their requests finish.
+func (s *clientState) retire() {
+	s.retired.Store(true)
+	s.closeIdle()
+}
+
+// reloadTransport sends the requests to the current state.
+type reloadTransport struct {
+	state atomic.Pointer[clientState]
+}
+
+var _ http.RoundTripper = (*reloadTransport)(nil)
+
+func (t *reloadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	state := t.state.Load()
+	state.active.Add(1)
+
+	resp, err := state.transport.RoundTrip(req)
+	// upgraded connections have a writable body, not tracked
+	if resp == nil || resp.Body == nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		state.done()
+
+		return resp, err
+	}
+
+	resp.Body = &stateBody{ReadCloser: resp.Body, state: state}
+
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the current transport, called by http.Client.
+func (t *reloadTransport) CloseIdleConnections() {
+	t.state.Load().closeIdle()
+}
+
+// stateBody finishes the request of the state on close.
+type stateBody struct {
+	io.ReadCloser
+
+	state *clientState
+	once  sync.Once
+}
+
+func (b *stateBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.state.done)
+
+	return err
+}
+
+// Reload replaces the configuration of the client, new requests use the new transport chain.
+//
+// Config replaces the one of Config.New, options of New and env values are applied again like in New.
+// In flight requests finish on the old transport, its connections are closed when they become idle.
+// Streams started before keep the old settings.
+//
+// Timeout can not be changed, ErrReload is returned.
+// Client.HTTP is used by the callers without lock and http.Client reads its Timeout on each request,
+// so Client.HTTP with the Timeout of New is kept also when the retry is enabled or disabled by the reload.
+func (c *Client) Reload(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	config := cfg.ToOption()
+
+	o, baseURL, err := newClientOption(clientOptions(c.options, config))
+	if err != nil {
+		return err
+	}
+
+	if o.Timeout != c.timeout {
+		return fmt.Errorf("%w: timeout can not be changed from %s to %s", ErrReload, c.timeout, o.Timeout)
+	}
+
+	base := c.base
+	if transport, ok := base.Transport.(*http.Transport); ok {
+		base.Transport = transport.Clone()
+
+		if c.baseOwned {
+			o.ownedTransport = base.Transport.(*http.Transport)
+		}
+	}
+
+	state, err := o.newClientState(&base, baseURL)
+	if err != nil {
+		return err
+	}
+
+	c.config = config
+	c.transport.state.Swap(state).retire()
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package klient
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientReload(t *testing.T) {
+	release := make(chan struct{})
+
+	var closed atomic.Int32
+
+	oldServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+
+		_, _ = w.Write([]byte("old " + r.Header.Get("X-Token")))
+	}))
+	oldServer.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed.Add(1)
+		}
+	}
+	oldServer.Start()
+	defer oldServer.Close()
+
+	newServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("new " + r.Header.Get("X-Token")))
+	}))
+	defer newServer.Close()
+
+	cfg := Config{BaseURL: oldServer.URL, Header: map[string][]string{"X-Token": {"a"}}}
+
+	client, err := cfg.New(WithDisableEnvValues(true), WithDisableRetry(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) string {
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, path, nil)
+
+		var body string
+		if err := client.Do(req, func(r *http.Response) error {
+			data, err := io.ReadAll(r.Body)
+			body = string(data)
+
+			return err
+		}); err != nil {
+			t.Error(err)
+		}
+
+		return body
+	}
+
+	inFlight := make(chan string)
+	go func() { inFlight <- get("/slow") }()
+
+	// wait the request to start on the old transport
+	for client.transport.state.Load().active.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := client.Reload(Config{BaseURL: newServer.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	if body := get("/"); body != "new " {
+		t.Errorf("expected new server without header, got %q", body)
+	}
+
+	close(release)
+
+	if body := <-inFlight; body != "old a" {
+		t.Errorf("expected in flight request on old server, got %q", body)
+	}
+
+	for deadline := time.Now().Add(time.Second); closed.Load() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("old connection not closed")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := client.Reload(Config{BaseURL: newServer.URL, Timeout: time.Second}); !errors.Is(err, ErrReload) {
+		t.Errorf("expected reload error, got %v", err)
+	}
+
+	if err := client.Reload(Config{BaseURL: "http://new host"}); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("expected invalid config error, got %v", err)
+	}
+
+	if body := get("/"); body != "new " {
+		t.Errorf("expected failed reloads to keep the client, got %q", body)
+	}
+
+	timeoutClient, err := (&Config{BaseURL: newServer.URL, Timeout: time.Second}).New(WithDisableEnvValues(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	disableRetry := true
+	if err := timeoutClient.Reload(Config{BaseURL: newServer.URL, Timeout: time.Second, DisableRetry: &disableRetry}); err != nil {
+		t.Fatal(err)
+	}
+
+	if timeoutClient.HTTP.Timeout != time.Second {
+		t.Errorf("expected timeout kept after disabling retry, got %s", timeoutClient.HTTP.Timeout)
+	}
+}
+
+func TestClientReloadHeader(t *testing.T) {
+	header := http.Header{"X-B": {"2"}}
+
+	client, err := New(
+		WithDisableBaseURLCheck(true),
+		WithDisableEnvValues(true),
+		WithHeader(header),
+		WithHeaderAdd(http.Header{"X-A": {"1"}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Reload(Config{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(header) != 1 {
+		t.Errorf("given header changed %v", header)
+	}
+
+	transport := client.transport.state.Load().transport.(*TransportKlient)
+	if got := transport.Header.Values("X-A"); len(got) != 1 {
+		t.Errorf("expected one X-A value, got %v", got)
+	}
+}
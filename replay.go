@@ -0,0 +1,278 @@
+package klient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/worldline-go/logz"
+)
+
+var ErrBodyNotReplayable = errors.New("request body is not replayable")
+
+// BodyNotReplayableError is returned when the request body cannot be sent again for a retry.
+type BodyNotReplayableError struct {
+	Method string
+	// URL is the request URL with redacted password.
+	URL string
+	Err error
+}
+
+func (e *BodyNotReplayableError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%v: %s %s", ErrBodyNotReplayable, e.Method, e.URL)
+	}
+
+	return fmt.Sprintf("%v: %s %s: %v", ErrBodyNotReplayable, e.Method, e.URL, e.Err)
+}
+
+func (e *BodyNotReplayableError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrBodyNotReplayable}
+	}
+
+	return []error{ErrBodyNotReplayable, e.Err}
+}
+
+func newBodyNotReplayableError(req *http.Request, err error) *BodyNotReplayableError {
+	return &BodyNotReplayableError{Method: req.Method, URL: req.URL.Redacted(), Err: err}
+}
+
+// DefaultBodyReplayMemoryLimit is the default in memory buffer size of BodyReplayBuffer.
+var DefaultBodyReplayMemoryLimit int64 = 1 << 20 // 1MB
+
+// BodyReplayMode is the handling of request bodies which cannot be rewound for retries.
+type BodyReplayMode string
+
+const (
+	// BodyReplayDefault lets retryablehttp read the whole body in memory.
+	BodyReplayDefault BodyReplayMode = ""
+	// BodyReplayBuffer buffers the body in memory up to the limit and spills the rest to a temp file.
+	// Temp file is removed when the response body is closed.
+	BodyReplayBuffer BodyReplayMode = "buffer"
+	// BodyReplayDisableRetry streams the body and disables retry for the request, also with a custom retry policy.
+	BodyReplayDisableRetry BodyReplayMode = "disable_retry"
+)
+
+// replayTransport makes request bodies replayable for the retry client.
+//
+// retryablehttp rewinds io.ReadSeeker bodies and reads any other body in memory.
+// Bodies with GetBody are rewound by calling GetBody, other bodies handled with the mode.
+type replayTransport struct {
+	base        http.RoundTripper
+	mode        BodyReplayMode
+	memoryLimit int64
+	log         logz.Adapter
+}
+
+var _ http.RoundTripper = (*replayTransport)(nil)
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return t.base.RoundTrip(req)
+	}
+
+	if _, ok := req.Body.(io.Seeker); ok {
+		return t.base.RoundTrip(req)
+	}
+
+	req2 := cloneRequest(req) // per RoundTripper contract
+
+	if req.GetBody != nil {
+		req2.Body = &getBodySeeker{getBody: req.GetBody, body: req.Body, req: req}
+
+		return t.base.RoundTrip(req2)
+	}
+
+	switch t.mode {
+	case BodyReplayBuffer:
+		body, size, err := bufferBody(req.Body, t.memoryLimit)
+		if err != nil {
+			return nil, newBodyNotReplayableError(req, err)
+		}
+
+		req2.Body = body
+		req2.ContentLength = size
+
+		resp, err := t.base.RoundTrip(req2)
+		if resp == nil || resp.Body == nil {
+			_ = body.Close()
+
+			return resp, err
+		}
+
+		// transport can read the body until the response is finished
+		resp.Body = &cleanupBody{ReadCloser: resp.Body, cleanup: body.Close}
+
+		return resp, err
+	case BodyReplayDisableRetry:
+		if t.log != nil {
+			t.log.Warn("request body is not replayable, retry disabled", "method", req.Method, "url", req.URL.Redacted())
+		}
+
+		req2 = req2.WithContext(ctxWithRetryDisabled(req.Context()))
+		req2.Body = &onceSeeker{body: req.Body, req: req}
+
+		return t.base.RoundTrip(req2)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// ctxKeyRetryDisabled stops the retry of the request regardless of the retry policy.
+const ctxKeyRetryDisabled ctxKey = "retry_disabled"
+
+// ctxWithRetryDisabled disables retry of the request, checked by retryDisabledPolicy.
+func ctxWithRetryDisabled(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxKeyRetryDisabled, true)
+}
+
+// retryDisabledPolicy wraps the retry policy to not retry the requests disabled by the transports.
+func retryDisabledPolicy(policy retryablehttp.CheckRetry) retryablehttp.CheckRetry {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if disabled, _ := ctx.Value(ctxKeyRetryDisabled).(bool); disabled {
+			if errCtx := ctx.Err(); errCtx != nil {
+				return false, errCtx
+			}
+
+			return false, nil
+		}
+
+		return policy(ctx, resp, err)
+	}
+}
+
+// cleanupBody calls cleanup once after closing the body.
+type cleanupBody struct {
+	io.ReadCloser
+
+	cleanup func() error
+	once    sync.Once
+}
+
+func (b *cleanupBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { _ = b.cleanup() })
+
+	return err
+}
+
+// getBodySeeker rewinds the body with calling GetBody.
+type getBodySeeker struct {
+	getBody func() (io.ReadCloser, error)
+	body    io.ReadCloser
+	req     *http.Request
+	read    bool
+}
+
+func (b *getBodySeeker) Read(p []byte) (int, error) {
+	b.read = true
+
+	return b.body.Read(p)
+}
+
+func (b *getBodySeeker) Close() error {
+	return b.body.Close()
+}
+
+func (b *getBodySeeker) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, newBodyNotReplayableError(b.req, errors.New("only seek to start supported"))
+	}
+
+	if !b.read {
+		return 0, nil
+	}
+
+	body, err := b.getBody()
+	if err != nil {
+		return 0, newBodyNotReplayableError(b.req, err)
+	}
+
+	_ = b.body.Close()
+	b.body = body
+	b.read = false
+
+	return 0, nil
+}
+
+// onceSeeker is a body which only could be rewound before reading.
+type onceSeeker struct {
+	body io.ReadCloser
+	req  *http.Request
+	read bool
+}
+
+func (b *onceSeeker) Read(p []byte) (int, error) {
+	b.read = true
+
+	return b.body.Read(p)
+}
+
+func (b *onceSeeker) Close() error {
+	return b.body.Close()
+}
+
+func (b *onceSeeker) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart || b.read {
+		return 0, newBodyNotReplayableError(b.req, nil)
+	}
+
+	return 0, nil
+}
+
+// bufferBody reads the body in memory up to memoryLimit and the rest to a temp file.
+//
+// Returned body removes the temp file on Close, errors are returned without ErrBodyNotReplayable.
+func bufferBody(body io.ReadCloser, memoryLimit int64) (io.ReadSeekCloser, int64, error) {
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, memoryLimit+1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("read body: %w", err)
+	}
+
+	if int64(len(data)) <= memoryLimit {
+		return nopSeekCloser{bytes.NewReader(data)}, int64(len(data)), nil
+	}
+
+	f, err := os.CreateTemp("", "klient-body-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("create temp file: %w", err)
+	}
+
+	tmp := &tempFile{File: f}
+
+	size, err := io.Copy(f, io.MultiReader(bytes.NewReader(data), body))
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		_ = tmp.Close()
+
+		return nil, 0, fmt.Errorf("write temp file: %w", err)
+	}
+
+	return tmp, size, nil
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	return errors.Join(f.File.Close(), os.Remove(f.Name()))
+}
@@ -0,0 +1,139 @@
+package klient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBodyReplay(t *testing.T) {
+	payload := strings.Repeat("klient", 100)
+
+	var attempts atomic.Int32
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		if attempts.Add(1) == 1 || string(body) != payload {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer httpServer.Close()
+
+	pipeRequest := func(t *testing.T) *http.Request {
+		t.Helper()
+
+		pr, pw := io.Pipe()
+		go func() {
+			_, _ = io.WriteString(pw, payload)
+			_ = pw.Close()
+		}()
+
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, "/upload", pr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return req
+	}
+
+	newClient := func(t *testing.T, opts ...OptionClientFn) *Client {
+		t.Helper()
+
+		client, err := New(append([]OptionClientFn{
+			WithBaseURL(httpServer.URL),
+			WithRetryWaitMin(10 * time.Millisecond),
+			WithRetryWaitMax(10 * time.Millisecond),
+		}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return client
+	}
+
+	t.Run("buffer with temp file", func(t *testing.T) {
+		attempts.Store(0)
+		tmpDir := t.TempDir()
+		t.Setenv("TMPDIR", tmpDir)
+
+		client := newClient(t, WithBodyReplay(BodyReplayBuffer, 10))
+
+		if err := client.Do(pipeRequest(t), UnexpectedResponse); err != nil {
+			t.Fatal(err)
+		}
+
+		if v := attempts.Load(); v != 2 {
+			t.Fatalf("expected 2 attempts, got %d", v)
+		}
+
+		if files, _ := os.ReadDir(tmpDir); len(files) != 0 {
+			t.Fatalf("expected temp file removed, got %d files", len(files))
+		}
+	})
+
+	t.Run("disable retry", func(t *testing.T) {
+		attempts.Store(0)
+		client := newClient(t, WithBodyReplay(BodyReplayDisableRetry, 0))
+
+		var respErr *ResponseError
+		if err := client.Do(pipeRequest(t), UnexpectedResponse); !errors.As(err, &respErr) {
+			t.Fatalf("expected response error, got %v", err)
+		}
+
+		if v := attempts.Load(); v != 1 {
+			t.Fatalf("expected 1 attempt, got %d", v)
+		}
+	})
+
+	t.Run("disable retry with custom policy", func(t *testing.T) {
+		attempts.Store(0)
+		client := newClient(t,
+			WithBodyReplay(BodyReplayDisableRetry, 0),
+			WithRetryPolicy(func(_ context.Context, resp *http.Response, err error) (bool, error) {
+				return resp == nil || resp.StatusCode >= 500, err
+			}),
+		)
+
+		var respErr *ResponseError
+		if err := client.Do(pipeRequest(t), UnexpectedResponse); !errors.As(err, &respErr) {
+			t.Fatalf("expected response error, got %v", err)
+		}
+
+		if v := attempts.Load(); v != 1 {
+			t.Fatalf("expected 1 attempt, got %d", v)
+		}
+	})
+
+	t.Run("not replayable", func(t *testing.T) {
+		attempts.Store(0)
+		client := newClient(t)
+
+		req := pipeRequest(t)
+		req.GetBody = func() (io.ReadCloser, error) {
+			return nil, errors.New("body is gone")
+		}
+
+		err := client.Do(req, UnexpectedResponse)
+
+		var replayErr *BodyNotReplayableError
+		if !errors.As(err, &replayErr) || !errors.Is(err, ErrBodyNotReplayable) {
+			t.Fatalf("expected BodyNotReplayableError, got %v", err)
+		}
+
+		if replayErr.Method != http.MethodPost || replayErr.URL != httpServer.URL+"/upload" {
+			t.Errorf("unexpected request %s %s", replayErr.Method, replayErr.URL)
+		}
+	})
+}
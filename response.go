@@ -37,6 +37,33 @@ func ResponseFuncJSON(data interface{}) func(*http.Response) error {
 	}
 }
 
+// ResponseJSON decodes the 2xx response body into T with json decoder.
+//
+// Non-2xx responses are returned as *ResponseErrorOf[E] with the body decoded into E,
+// application/problem+json responses are also decoded into its Problem field.
+//
+//	func (GetUser) Response(resp *http.Response) (User, error) {
+//		return klient.ResponseJSON[User, klient.ProblemDetails](resp)
+//	}
+func ResponseJSON[T, E any](resp *http.Response) (T, error) {
+	var v T
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return v, ErrResponseOf[E](resp)
+	}
+
+	// 204s, for example
+	if resp.ContentLength == 0 {
+		return v, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		return v, fmt.Errorf("decode response body: %w", err)
+	}
+
+	return v, nil
+}
+
 // LimitedResponse not close body, retry library draining it.
 //   - Return limited response body
 //   - Ready all body and assign it back to resp.Body
@@ -0,0 +1,300 @@
+package klient
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrSSEReconnect = errors.New("server-sent events reconnect limit reached")
+
+// Event is a server-sent event.
+type Event struct {
+	// ID is the last event ID.
+	ID string
+	// Event is the event type, default is "message".
+	Event string
+	// Data is the event data, multiple data lines joined with "\n".
+	Data string
+	// Retry is the reconnection time sent by the server.
+	Retry time.Duration
+}
+
+type optionSSEValue struct {
+	Header       http.Header
+	LastEventID  string
+	ReconnectMax int
+}
+
+type OptionSSEFn func(*optionSSEValue)
+
+type OptionSSEHolder struct{}
+
+var OptionSSE = OptionSSEHolder{}
+
+// WithHeader sets extra headers of the server-sent events request.
+func (OptionSSEHolder) WithHeader(header http.Header) OptionSSEFn {
+	return func(o *optionSSEValue) {
+		o.Header = header
+	}
+}
+
+// WithLastEventID sets the initial Last-Event-ID header.
+func (OptionSSEHolder) WithLastEventID(id string) OptionSSEFn {
+	return func(o *optionSSEValue) {
+		o.LastEventID = id
+	}
+}
+
+// WithReconnectMax sets the maximum number of consecutive failed reconnections.
+//
+// Default is the client's RetryMax, 0 when retry of the client is disabled.
+// Negative value means reconnect forever.
+func (OptionSSEHolder) WithReconnectMax(reconnectMax int) OptionSSEFn {
+	return func(o *optionSSEValue) {
+		o.ReconnectMax = reconnectMax
+	}
+}
+
+// Events connects to the server-sent events endpoint and iterates over received events.
+//
+// Connection uses client's transport chain with base URL, default headers, TLS, inject function and middlewares,
+// but not the retry, timeout and WithMaxResponseBodySize of the client; stop with canceling ctx or breaking the loop.
+//
+// When the connection is lost it reconnects with Last-Event-ID header and waits
+// with server's retry value or the client's backoff settings.
+//
+//	for event, err := range client.Events(ctx, "/events") {
+//		if err != nil {
+//			return err
+//		}
+//		// use event
+//	}
+func (c *Client) Events(ctx context.Context, path string, opts ...OptionSSEFn) iter.Seq2[Event, error] {
+	stream := c.transport.state.Load().stream
+
+	o := optionSSEValue{
+		ReconnectMax: stream.RetryMax,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return func(yield func(Event, error) bool) {
+		httpClient := &http.Client{Transport: stream.Transport}
+
+		lastEventID := o.LastEventID
+		var retry time.Duration
+
+		for attempt := 0; ; {
+			resp, err := c.eventsConnect(ctx, httpClient, path, lastEventID, o.Header)
+			if err != nil {
+				var errSSE *sseError
+				if errors.As(err, &errSSE) {
+					if errSSE.err != nil {
+						yield(Event{}, errSSE.err)
+					}
+
+					return
+				}
+
+				stream.logWarn("server-sent events connect failed", err)
+			} else {
+				attempt = 0
+
+				for event, errRead := range readEvents(ctx, resp.Body) {
+					if errRead != nil {
+						err = errRead
+						stream.logWarn("server-sent events connection lost", err)
+
+						break
+					}
+
+					if event.Retry > 0 {
+						retry = event.Retry
+					}
+
+					lastEventID = event.ID
+
+					// only id and retry
+					if event.Event == "" {
+						continue
+					}
+
+					if !yield(event, nil) {
+						return
+					}
+				}
+			}
+
+			if ctx.Err() != nil {
+				yield(Event{}, ctx.Err())
+
+				return
+			}
+
+			if o.ReconnectMax >= 0 && attempt >= o.ReconnectMax {
+				yield(Event{}, fmt.Errorf("%w: %w", ErrSSEReconnect, err))
+
+				return
+			}
+
+			wait := retry
+			if wait == 0 {
+				wait = stream.Backoff(stream.RetryWaitMin, stream.RetryWaitMax, attempt, nil)
+			}
+
+			attempt++
+
+			if err := sleepClock(ctx, stream.Clock, wait); err != nil {
+				yield(Event{}, err)
+
+				return
+			}
+		}
+	}
+}
+
+// sseError is a not reconnectable result of the server-sent events request.
+//
+// Nil err means the server asked to stop.
+type sseError struct {
+	err error
+}
+
+func (e *sseError) Error() string {
+	if e.err == nil {
+		return "server-sent events stopped"
+	}
+
+	return e.err.Error()
+}
+
+func (c *Client) eventsConnect(ctx context.Context, httpClient *http.Client, path, lastEventID string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctxWithStream(ctx), http.MethodGet, path, nil)
+	if err != nil {
+		return nil, &sseError{err: fmt.Errorf("%w: %w", ErrCreateRequest, err)}
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRequest, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		// server asks to stop reconnecting
+		DrainBody(resp.Body)
+
+		return nil, &sseError{}
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= http.StatusInternalServerError:
+		defer DrainBody(resp.Body)
+
+		return nil, ErrResponse(resp)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		defer DrainBody(resp.Body)
+
+		return nil, &sseError{err: ErrResponse(resp)}
+	}
+
+	return resp, nil
+}
+
+func (s streamValue) logWarn(msg string, err error) {
+	if s.Logger != nil {
+		s.Logger.Warn(msg, "error", err)
+	}
+}
+
+// readEvents parses the event stream in body.
+//
+// Yields io.EOF error when the stream ends, body is closed after iteration.
+func readEvents(ctx context.Context, body io.ReadCloser) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		reader := newStreamReader(ctx, body)
+		defer DrainBody(reader)
+
+		var (
+			event Event
+			data  strings.Builder
+		)
+
+		bufReader := bufio.NewReader(reader)
+		for {
+			line, err := bufReader.ReadString('\n')
+			if err != nil {
+				yield(Event{}, err)
+
+				return
+			}
+
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+			if line == "" {
+				// dispatch, event without data only carries id and retry
+				if data.Len() > 0 {
+					event.Data = strings.TrimSuffix(data.String(), "\n")
+					if event.Event == "" {
+						event.Event = "message"
+					}
+				} else {
+					event = Event{ID: event.ID, Retry: event.Retry}
+				}
+
+				if !yield(event, nil) {
+					return
+				}
+
+				event = Event{ID: event.ID}
+				data.Reset()
+
+				continue
+			}
+
+			if strings.HasPrefix(line, ":") {
+				// comment
+				continue
+			}
+
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+
+			switch field {
+			case "event":
+				event.Event = value
+			case "data":
+				data.WriteString(value)
+				data.WriteByte('\n')
+			case "id":
+				if !strings.Contains(value, "\x00") {
+					event.ID = value
+				}
+			case "retry":
+				if v, err := strconv.ParseUint(value, 10, 63); err == nil {
+					event.Retry = time.Duration(v) * time.Millisecond
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package klient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Events(t *testing.T) {
+	var connections atomic.Int32
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Info") != "test" || r.Header.Get("Accept") != "text/event-stream" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		switch connections.Add(1) {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, ": comment\nretry: 10\n\n")
+			fmt.Fprint(w, "id: 1\nevent: greeting\ndata: hello\ndata: world\n\n")
+			w.(http.Flusher).Flush()
+			// longer than the client timeout
+			time.Sleep(200 * time.Millisecond)
+			fmt.Fprint(w, "id: 2\ndata: second\r\n\r\n")
+		case 2:
+			if r.Header.Get("Last-Event-ID") != "2" {
+				w.WriteHeader(http.StatusBadRequest)
+
+				return
+			}
+
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 3:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "id: 3\ndata: third\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer httpServer.Close()
+
+	client, err := New(
+		WithBaseURL(httpServer.URL),
+		WithHeaderSet(http.Header{"X-Info": []string{"test"}}),
+		WithTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	for event, err := range client.Events(t.Context(), "/events") {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		events = append(events, event)
+	}
+
+	want := []Event{
+		{ID: "1", Event: "greeting", Data: "hello\nworld"},
+		{ID: "2", Event: "message", Data: "second"},
+		{ID: "3", Event: "message", Data: "third"},
+	}
+
+	if fmt.Sprint(events) != fmt.Sprint(want) {
+		t.Fatalf("expected %v, got %v", want, events)
+	}
+
+	if v := connections.Load(); v != 4 {
+		t.Fatalf("expected 4 connections, got %d", v)
+	}
+}
+
+func TestClient_EventsTransport(t *testing.T) {
+	var connections atomic.Int32
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections.Add(1)
+
+		if r.Header.Get("X-Middleware") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer httpServer.Close()
+
+	client, err := New(
+		WithBaseURL(httpServer.URL),
+		WithDisableRetry(true),
+		WithRoundTripper(func(_ context.Context, base http.RoundTripper) (http.RoundTripper, error) {
+			return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				r = r.Clone(r.Context())
+				r.Header.Set("X-Middleware", "true")
+
+				return base.RoundTrip(r)
+			}), nil
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range client.Events(t.Context(), "/events") {
+		if !errors.Is(err, ErrSSEReconnect) {
+			t.Fatalf("expected reconnect error, got %v", err)
+		}
+	}
+
+	// not reconnected when retry is disabled
+	if v := connections.Load(); v != 1 {
+		t.Fatalf("expected 1 connection, got %d", v)
+	}
+}
+
+func TestClient_EventsMaxResponseBodySize(t *testing.T) {
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		for i := range 10 {
+			fmt.Fprintf(w, "id: %d\ndata: %s\n\n", i, strings.Repeat("a", 100))
+		}
+	}))
+	defer httpServer.Close()
+
+	client, err := New(WithBaseURL(httpServer.URL), WithMaxResponseBodySize(100), WithDisableRetry(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events int
+	for _, err := range client.Events(t.Context(), "/events") {
+		if err != nil {
+			// end of the stream is not reconnected without retry
+			if !errors.Is(err, ErrSSEReconnect) || errors.Is(err, ErrResponseTooLarge) {
+				t.Fatalf("expected events without the client limit, got %v", err)
+			}
+
+			break
+		}
+
+		events++
+	}
+
+	if events != 10 {
+		t.Fatalf("expected 10 events, got %d", events)
+	}
+}
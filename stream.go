@@ -0,0 +1,175 @@
+package klient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+)
+
+var ErrStreamFormat = errors.New("invalid stream format")
+
+// StreamJSONLines sends the request and iterates over the NDJSON (JSON lines) response body.
+//
+// The request is sent when the iteration starts and the body is kept open during iteration.
+// Body is drained and closed when iteration ends or stopped early.
+// A non-2xx response yields ResponseError.
+//
+//	for v, err := range klient.StreamJSONLines[Record](client.HTTP, req) {
+//		if err != nil {
+//			return err
+//		}
+//		// use v
+//	}
+func StreamJSONLines[T any](c *http.Client, req *http.Request) iter.Seq2[T, error] {
+	return stream(c, req, DecodeJSONLines[T])
+}
+
+// StreamJSONArray sends the request and iterates over elements of the top-level JSON array response body.
+//
+// Behaves same as StreamJSONLines.
+func StreamJSONArray[T any](c *http.Client, req *http.Request) iter.Seq2[T, error] {
+	return stream(c, req, DecodeJSONArray[T])
+}
+
+func stream[T any](c *http.Client, req *http.Request, decode func(context.Context, io.ReadCloser) iter.Seq2[T, error]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var empty T
+
+		resp, err := c.Do(req)
+		if err != nil {
+			yield(empty, fmt.Errorf("%w: %w", ErrRequest, err))
+
+			return
+		}
+
+		if err := UnexpectedResponse(resp); err != nil {
+			DrainBody(resp.Body)
+			yield(empty, err)
+
+			return
+		}
+
+		for v, err := range decode(req.Context(), resp.Body) {
+			if !yield(v, err) {
+				return
+			}
+		}
+	}
+}
+
+// DecodeJSONLines iterates over the NDJSON (JSON lines) values of body.
+//
+// Reading stops with the context error when ctx is canceled.
+// Body is drained and closed when iteration ends or stopped early.
+func DecodeJSONLines[T any](ctx context.Context, body io.ReadCloser) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		reader := newStreamReader(ctx, body)
+		defer DrainBody(reader)
+
+		decoder := json.NewDecoder(reader)
+		for {
+			var v T
+			if err := streamErr(ctx); err != nil {
+				yield(v, err)
+
+				return
+			}
+
+			if err := decoder.Decode(&v); err != nil {
+				if errors.Is(err, io.EOF) {
+					return
+				}
+
+				yield(v, fmt.Errorf("decode stream: %w", err))
+
+				return
+			}
+
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// DecodeJSONArray iterates over elements of the top-level JSON array in body.
+//
+// Reading stops with the context error when ctx is canceled.
+// Body is drained and closed when iteration ends or stopped early.
+func DecodeJSONArray[T any](ctx context.Context, body io.ReadCloser) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var empty T
+
+		reader := newStreamReader(ctx, body)
+		defer DrainBody(reader)
+
+		decoder := json.NewDecoder(reader)
+
+		token, err := decoder.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			yield(empty, fmt.Errorf("decode stream: %w", err))
+
+			return
+		}
+
+		// null is an empty array
+		if token == nil {
+			return
+		}
+
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			yield(empty, fmt.Errorf("%w: expected array, got %v", ErrStreamFormat, token))
+
+			return
+		}
+
+		for decoder.More() {
+			var v T
+			if err := streamErr(ctx); err != nil {
+				yield(v, err)
+
+				return
+			}
+
+			if err := decoder.Decode(&v); err != nil {
+				yield(v, fmt.Errorf("decode stream: %w", err))
+
+				return
+			}
+
+			if !yield(v, nil) {
+				return
+			}
+		}
+
+		if _, err := decoder.Token(); err != nil {
+			yield(empty, fmt.Errorf("decode stream: %w", err))
+		}
+	}
+}
+
+func newStreamReader(ctx context.Context, body io.ReadCloser) *MultiReader {
+	reader := NewMultiReader(body)
+	if ctx != nil {
+		reader.SetContext(ctx)
+	}
+
+	return reader
+}
+
+// streamErr returns the context error, decoder could serve buffered values without reading.
+func streamErr(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,115 @@
+package klient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+
+	return nil
+}
+
+func TestStream(t *testing.T) {
+	type record struct {
+		ID int `json:"id"`
+	}
+
+	t.Run("json lines", func(t *testing.T) {
+		httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i := range 5 {
+				fmt.Fprintf(w, "{\"id\": %d}\n", i)
+			}
+		}))
+		defer httpServer.Close()
+
+		client, err := NewPlain(WithBaseURL(httpServer.URL))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "/export", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []int{}
+		for v, err := range StreamJSONLines[record](client.HTTP, req) {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids = append(ids, v.ID)
+		}
+
+		if fmt.Sprint(ids) != "[0 1 2 3 4]" {
+			t.Fatalf("unexpected ids %v", ids)
+		}
+	})
+
+	t.Run("json array", func(t *testing.T) {
+		body := &closeRecorder{Reader: strings.NewReader(`[{"id":1}, {"id":2}, {"id":3}]`)}
+
+		ids := []int{}
+		for v, err := range DecodeJSONArray[record](t.Context(), body) {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ids = append(ids, v.ID)
+			if len(ids) == 2 {
+				break
+			}
+		}
+
+		if fmt.Sprint(ids) != "[1 2]" {
+			t.Fatalf("unexpected ids %v", ids)
+		}
+
+		if !body.closed {
+			t.Fatal("expected body to be closed")
+		}
+	})
+
+	t.Run("not array", func(t *testing.T) {
+		for _, err := range DecodeJSONArray[record](t.Context(), io.NopCloser(strings.NewReader(`{"id":1}`))) {
+			if !errors.Is(err, ErrStreamFormat) {
+				t.Fatalf("expected ErrStreamFormat, got %v", err)
+			}
+		}
+	})
+
+	t.Run("context cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+
+		var count int
+		var gotErr error
+		for _, err := range DecodeJSONLines[record](ctx, io.NopCloser(strings.NewReader("{\"id\":1}\n{\"id\":2}\n"))) {
+			if err != nil {
+				gotErr = err
+
+				break
+			}
+
+			count++
+			cancel()
+		}
+
+		if count != 1 || !errors.Is(gotErr, context.Canceled) {
+			t.Fatalf("expected cancel after first value, got count %d err %v", count, gotErr)
+		}
+	})
+}
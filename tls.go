@@ -2,39 +2,326 @@ package klient
 
 import (
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
 
-	"github.com/twmb/tlscfg"
+	"github.com/youmark/pkcs8"
+	"software.sslmate.com/src/go-pkcs12"
 )
 
 // TLSConfig contains options for TLS authentication.
+//
+// Client certificate is loaded from the cert and key PEM values or files, or from a PKCS#12 bundle.
 type TLSConfig struct {
 	// CertFile is the path to the client's TLS certificate.
 	// Should be use with KeyFile.
-	CertFile string `cfg:"cert_file"`
+	CertFile string `cfg:"cert_file" log:"-"`
 	// KeyFile is the path to the client's TLS key.
 	// Should be use with CertFile.
-	KeyFile string `cfg:"key_file"`
+	KeyFile string `cfg:"key_file" log:"-"`
 	// CAFile is the path to the CA certificate.
 	// If empty, the server's root CA set will be used.
-	CAFile string `cfg:"ca_file"`
+	CAFile string `cfg:"ca_file" log:"-"`
+
+	// Cert, Key and CA are the PEM values instead of the files.
+	Cert string `cfg:"cert" log:"-"`
+	Key  string `cfg:"key" log:"-"`
+	CA   string `cfg:"ca" log:"-"`
+	// KeyPassword decrypts an encrypted key, PKCS#8 or legacy PEM encryption.
+	KeyPassword string `cfg:"key_password" log:"-"`
+
+	// PKCS12File is the path to the .p12 bundle of the client's certificate and key.
+	PKCS12File string `cfg:"pkcs12_file" log:"-"`
+	// PKCS12 is the base64 encoded .p12 bundle instead of the file.
+	PKCS12 string `cfg:"pkcs12" log:"-"`
+	// PKCS12Password is the password of the .p12 bundle.
+	PKCS12Password string `cfg:"pkcs12_password" log:"-"`
+
+	// DisableSystemCA verifies the server only with CA and CAFile instead of adding them to the system pool.
+	DisableSystemCA bool `cfg:"disable_system_ca"`
+
+	// MinVersion and MaxVersion are 1.0, 1.1, 1.2 or 1.3. Default is the crypto/tls default.
+	MinVersion string `cfg:"min_version"`
+	MaxVersion string `cfg:"max_version"`
+	// CipherSuites are the names of the TLS 1.0-1.2 cipher suites, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+	// TLS 1.3 suites are not configurable.
+	CipherSuites []string `cfg:"cipher_suites"`
+	// ServerName overrides the host name to verify the server certificate and for SNI.
+	ServerName string `cfg:"server_name"`
+
+	// Pins are the SPKI SHA-256 pins in base64, optionally with "sha256/" prefix, see SPKIPin.
+	// Connection fails if no certificate of the verified chain matches a pin or a backup pin.
+	// Extra certificates sent by the server are not matched, with InsecureSkipVerify only the leaf is matched.
+	Pins []string `cfg:"pins"`
+	// BackupPins are the pins of the next keys to rotate the server certificate without downtime.
+	BackupPins []string `cfg:"backup_pins"`
+	// PinsReportOnly logs the mismatches with the client logger instead of failing the connection.
+	PinsReportOnly bool `cfg:"pins_report_only"`
+
+	// ReloadInterval enables reloading of the changed files, checked at most once per interval on new connections.
+	// Last good certificate and CA are used when reload fails, failures are reported to WithTLSReloadHook.
+	//
+	// When a CA is configured, the standard verification is replaced: InsecureSkipVerify is set and
+	// VerifyConnection checks the server certificate chain and host name with the current CA pool,
+	// since RootCAs of a tls.Config can not be changed.
+	ReloadInterval time.Duration `cfg:"reload_interval"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
 }
 
 // Generate returns a tls.Config based on the TLSConfig.
-//
-// If the TLSConfig is empty, nil is returned.
 func (t TLSConfig) Generate() (*tls.Config, error) {
-	opts := []tlscfg.Opt{}
+	return t.generate(nil, nil)
+}
+
+// generate returns the tls.Config, onError is called for failed reloads and onPinReport for report only pin mismatches.
+func (t TLSConfig) generate(onError, onPinReport func(error)) (*tls.Config, error) {
+	verifyPins, err := t.pinVerifier(onPinReport)
+	if err != nil {
+		return nil, err
+	}
+
+	var tlsConfig *tls.Config
+
+	if t.ReloadInterval > 0 {
+		tlsConfig, err = newTLSReloader(t, onError, verifyPins)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		tlsConfig = &tls.Config{}
+
+		cert, err := t.certificate()
+		if err != nil {
+			return nil, err
+		}
+
+		if cert != nil {
+			tlsConfig.Certificates = []tls.Certificate{*cert}
+		}
+
+		if tlsConfig.RootCAs, err = t.rootCAs(); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.MinVersion != "" {
+		version, ok := tlsVersions[t.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported tls min version %q", t.MinVersion)
+		}
+
+		tlsConfig.MinVersion = version
+	}
+
+	if t.MaxVersion != "" {
+		version, ok := tlsVersions[t.MaxVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported tls max version %q", t.MaxVersion)
+		}
+
+		tlsConfig.MaxVersion = version
+	}
+
+	for _, name := range t.CipherSuites {
+		id, ok := cipherSuite(name)
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+
+		tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, id)
+	}
+
+	tlsConfig.ServerName = t.ServerName
+
+	// reloader checks the pins with the chains of its own verification
+	if tlsConfig.VerifyConnection == nil {
+		tlsConfig.VerifyConnection = verifyConnectionPins(verifyPins)
+	}
+
+	return tlsConfig, nil
+}
+
+// hasCertificate reports a client certificate is configured.
+func (t TLSConfig) hasCertificate() bool {
+	return t.PKCS12File != "" || t.PKCS12 != "" || ((t.Cert != "" || t.CertFile != "") && (t.Key != "" || t.KeyFile != ""))
+}
+
+// certificateFiles returns the files of the client certificate to watch.
+func (t TLSConfig) certificateFiles() []string {
+	var files []string
+	for _, file := range []string{t.CertFile, t.KeyFile, t.PKCS12File} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	return files
+}
+
+// certificate returns the client certificate, nil if not configured.
+func (t TLSConfig) certificate() (*tls.Certificate, error) {
+	if !t.hasCertificate() {
+		return nil, nil
+	}
+
+	if t.PKCS12File != "" || t.PKCS12 != "" {
+		return t.pkcs12Certificate()
+	}
+
+	certPEM, err := valueOrFile(t.Cert, t.CertFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
 
-	// load client cert
-	if t.CertFile != "" && t.KeyFile != "" {
-		opts = append(opts, tlscfg.WithDiskKeyPair(t.CertFile, t.KeyFile))
+	keyPEM, err := valueOrFile(t.Key, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+
+	keyPEM, err = decryptKey(keyPEM, t.KeyPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	return &cert, nil
+}
+
+func (t TLSConfig) pkcs12Certificate() (*tls.Certificate, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if t.PKCS12 != "" {
+		data, err = base64.StdEncoding.DecodeString(t.PKCS12)
+	} else {
+		data, err = os.ReadFile(t.PKCS12File)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pkcs12: %w", err)
+	}
+
+	key, leaf, chain, err := pkcs12.DecodeChain(data, t.PKCS12Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode pkcs12: %w", err)
+	}
+
+	cert := tls.Certificate{
+		Certificate: [][]byte{leaf.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}
+
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+
+	return &cert, nil
+}
+
+// rootCAs returns the pool of the system and configured CA certificates, nil for the default system pool.
+func (t TLSConfig) rootCAs() (*x509.CertPool, error) {
+	if t.CA == "" && t.CAFile == "" {
+		if t.DisableSystemCA {
+			return nil, errors.New("disable_system_ca requires ca or ca_file")
+		}
+
+		return nil, nil
+	}
+
+	pool := x509.NewCertPool()
+	if !t.DisableSystemCA {
+		if systemPool, err := x509.SystemCertPool(); err == nil {
+			pool = systemPool
+		}
+	}
+
+	if t.CA != "" && !pool.AppendCertsFromPEM([]byte(t.CA)) {
+		return nil, errors.New("no certificate in ca")
 	}
 
-	// load CA cert
-	opts = append(opts, tlscfg.WithSystemCertPool())
 	if t.CAFile != "" {
-		opts = append(opts, tlscfg.WithDiskCA(t.CAFile, tlscfg.ForClient))
+		data, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file: %w", err)
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate in %s", t.CAFile)
+		}
+	}
+
+	return pool, nil
+}
+
+func valueOrFile(value, file string) ([]byte, error) {
+	if value != "" {
+		return []byte(value), nil
+	}
+
+	return os.ReadFile(file)
+}
+
+// decryptKey returns the key as not encrypted PEM, not encrypted keys are returned as is.
+func decryptKey(keyPEM []byte, password string) ([]byte, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return keyPEM, nil
+	}
+
+	switch {
+	case block.Type == "ENCRYPTED PRIVATE KEY":
+		key, err := pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key: %w", err)
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key: %w", err)
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	//nolint:staticcheck // legacy encryption of openssl keys
+	case x509.IsEncryptedPEMBlock(block):
+		//nolint:staticcheck // legacy encryption of openssl keys
+		der, err := x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key: %w", err)
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}), nil
+	}
+
+	return keyPEM, nil
+}
+
+// cipherSuite returns the id of the cipher suite name, insecure ones are included.
+func cipherSuite(name string) (uint16, bool) {
+	for _, suites := range [][]*tls.CipherSuite{tls.CipherSuites(), tls.InsecureCipherSuites()} {
+		for _, suite := range suites {
+			if suite.Name == name {
+				return suite.ID, true
+			}
+		}
 	}
 
-	return tlscfg.New(opts...)
+	return 0, false
 }
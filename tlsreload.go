@@ -0,0 +1,200 @@
+package klient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+var ErrTLSReload = errors.New("tls reload failed")
+
+// tlsReloader loads the changed certificate and CA files of TLSConfig on new connections.
+type tlsReloader struct {
+	config     TLSConfig
+	onError    func(error)
+	verifyPins pinCheck
+
+	mutex   sync.Mutex
+	checked time.Time
+	certMod []time.Time
+	caMod   []time.Time
+	cert    *tls.Certificate
+	pool    *x509.CertPool
+}
+
+// newTLSReloader returns the tls.Config using the reloaded files, first load must succeed.
+//
+// CA is verified in VerifyConnection since RootCAs can not be changed, so InsecureSkipVerify is set.
+// Pins are checked with the chains of this verification.
+func newTLSReloader(t TLSConfig, onError func(error), verifyPins pinCheck) (*tls.Config, error) {
+	r := &tlsReloader{config: t, onError: onError, verifyPins: verifyPins}
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	r.checked = time.Now()
+
+	tlsConfig := &tls.Config{}
+
+	if t.hasCertificate() {
+		tlsConfig.GetClientCertificate = r.getClientCertificate
+	}
+
+	if r.pool != nil {
+		//nolint:gosec // verified in VerifyConnection
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyConnection = r.verifyConnection
+	}
+
+	return tlsConfig, nil
+}
+
+// load reads the files changed after the last load, nothing changes on error.
+func (r *tlsReloader) load() error {
+	var errs []error
+
+	if err := r.loadCert(); err != nil {
+		errs = append(errs, fmt.Errorf("%w: certificate: %w", ErrTLSReload, err))
+	}
+
+	if err := r.loadCA(); err != nil {
+		errs = append(errs, fmt.Errorf("%w: ca: %w", ErrTLSReload, err))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (r *tlsReloader) loadCert() error {
+	if !r.config.hasCertificate() {
+		return nil
+	}
+
+	mod, err := modTimes(r.config.certificateFiles())
+	if err != nil {
+		return err
+	}
+
+	if r.cert != nil && slices.Equal(mod, r.certMod) {
+		return nil
+	}
+
+	// files can be written one by one, a mismatched pair is tried again on the next check
+	cert, err := r.config.certificate()
+	if err != nil {
+		return err
+	}
+
+	r.cert, r.certMod = cert, mod
+
+	return nil
+}
+
+func (r *tlsReloader) loadCA() error {
+	if r.config.CA == "" && r.config.CAFile == "" {
+		return nil
+	}
+
+	mod, err := modTimes([]string{r.config.CAFile})
+	if err != nil {
+		return err
+	}
+
+	if r.pool != nil && slices.Equal(mod, r.caMod) {
+		return nil
+	}
+
+	pool, err := r.config.rootCAs()
+	if err != nil {
+		return err
+	}
+
+	r.pool, r.caMod = pool, mod
+
+	return nil
+}
+
+// check loads the changed files once per interval.
+func (r *tlsReloader) check() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if time.Since(r.checked) < r.config.ReloadInterval {
+		return
+	}
+
+	r.checked = time.Now()
+
+	if err := r.load(); err != nil && r.onError != nil {
+		r.onError(err)
+	}
+}
+
+func (r *tlsReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.check()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.cert, nil
+}
+
+// verifyConnection verifies the server certificate with the current CA pool like the default verification,
+// then the pins with the verified chains.
+func (r *tlsReloader) verifyConnection(cs tls.ConnectionState) error {
+	r.check()
+
+	r.mutex.Lock()
+	pool := r.pool
+	r.mutex.Unlock()
+
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: no server certificate")
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+	}
+
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	chains, err := cs.PeerCertificates[0].Verify(opts)
+	if err != nil {
+		return &tls.CertificateVerificationError{UnverifiedCertificates: cs.PeerCertificates, Err: err}
+	}
+
+	if r.verifyPins != nil {
+		return r.verifyPins(cs, chains)
+	}
+
+	return nil
+}
+
+// modTimes returns the modification times of the files, empty paths are skipped.
+func modTimes(files []string) ([]time.Time, error) {
+	var mod []time.Time
+
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+
+		mod = append(mod, info.ModTime())
+	}
+
+	return mod, nil
+}
@@ -99,6 +99,7 @@ func cloneRequest(r *http.Request) *http.Request {
 type retryTimeoutTransport struct {
 	base    http.RoundTripper
 	timeout time.Duration
+	clock   Clock
 }
 
 var _ http.RoundTripper = (*retryTimeoutTransport)(nil)
@@ -106,7 +107,7 @@ var _ http.RoundTripper = (*retryTimeoutTransport)(nil)
 // RoundTrip implements http.RoundTripper and adds a timeout context to each request.
 func (t *retryTimeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	// Create a timeout context for this specific attempt
-	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	ctx, cancel := contextWithTimeout(req.Context(), t.clock, t.timeout)
 	defer cancel()
 
 	// Clone the request with the timeout context
@@ -0,0 +1,92 @@
+package klient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransportOptions(t *testing.T) {
+	for _, http2 := range []bool{false, true} {
+		cfg := Config{
+			HTTP2: &http2,
+			Transport: &TransportConfig{
+				MaxConnections:        20,
+				MaxConnsPerHost:       5,
+				DialTimeout:           time.Second,
+				TLSHandshakeTimeout:   2 * time.Second,
+				ResponseHeaderTimeout: 3 * time.Second,
+				ExpectContinueTimeout: 4 * time.Second,
+			},
+		}
+
+		client, err := cfg.New(WithDisableBaseURLCheck(true), WithDisableEnvValues(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		transport := client.transport.state.Load().base
+
+		if transport.MaxIdleConns != 20 || transport.MaxIdleConnsPerHost != 20 || transport.MaxConnsPerHost != 5 {
+			t.Errorf("http2 %v: unexpected connection limits %d %d %d", http2, transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+		}
+
+		if transport.DialContext == nil {
+			t.Errorf("http2 %v: dialer not set", http2)
+		}
+
+		if transport.IdleConnTimeout != 90*time.Second {
+			t.Errorf("http2 %v: unset value changed %s", http2, transport.IdleConnTimeout)
+		}
+
+		if transport.TLSHandshakeTimeout != 2*time.Second ||
+			transport.ResponseHeaderTimeout != 3*time.Second ||
+			transport.ExpectContinueTimeout != 4*time.Second {
+			t.Errorf("http2 %v: unexpected timeouts", http2)
+		}
+	}
+}
+
+func TestTransportOptionsUserTransport(t *testing.T) {
+	for _, opt := range []func(*http.Transport) OptionClientFn{
+		func(transport *http.Transport) OptionClientFn { return WithBaseTransport(transport) },
+		func(transport *http.Transport) OptionClientFn {
+			return WithHTTPClient(&http.Client{Transport: transport})
+		},
+	} {
+		transport := &http.Transport{MaxIdleConns: 1, MaxIdleConnsPerHost: 1, IdleConnTimeout: time.Minute}
+		want := transport.Clone()
+
+		if _, err := NewPlain(opt(transport), WithMaxConnections(20), WithDialTimeout(time.Second), WithIdleConnTimeout(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+
+		if transport.MaxIdleConns != want.MaxIdleConns ||
+			transport.MaxIdleConnsPerHost != want.MaxIdleConnsPerHost ||
+			transport.IdleConnTimeout != want.IdleConnTimeout ||
+			transport.DialContext != nil {
+			t.Errorf("user transport changed %+v", transport)
+		}
+	}
+}
+
+func TestTransportResponseHeaderTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewPlain(WithBaseURL(server.URL), WithResponseHeaderTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, "/", nil)
+	if err := client.Do(req, func(*http.Response) error { return nil }); err == nil {
+		t.Fatal("expected response header timeout error")
+	}
+}
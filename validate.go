@@ -0,0 +1,239 @@
+package klient
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// FieldError is a validation problem of a configuration field.
+//
+// Field is the path of the `cfg` tags, e.g. tls.key_file.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+func (e *FieldError) Unwrap() error {
+	return ErrInvalidConfig
+}
+
+// Validate returns all problems of the configuration joined, nil if it is valid.
+//
+// Unset values are validated with their defaults, each problem is a *FieldError.
+func (c Config) Validate() error {
+	o := newOptionClientValue([]OptionClientFn{c.ToOption()})
+
+	return o.validate()
+}
+
+// validate checks the merged option values.
+func (o *optionClientValue) validate() error {
+	var errs []error
+
+	add := func(field, format string, args ...any) {
+		errs = append(errs, &FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// relative base url is allowed, New warns about it
+	if o.BaseURL != "" {
+		if _, err := url.Parse(o.BaseURL); err != nil {
+			add("base_url", "invalid url: %v", err)
+		}
+	}
+
+	if o.Timeout < 0 {
+		add("timeout", "must not be negative")
+	}
+
+	if o.RetryMax < 0 {
+		add("retry_max", "must not be negative")
+	}
+
+	if o.RetryWaitMin < 0 {
+		add("retry_wait_min", "must not be negative")
+	}
+
+	if o.RetryWaitMax < 0 {
+		add("retry_wait_max", "must not be negative")
+	}
+
+	// defaults are in order, effective values are inverted only with a set value
+	if o.RetryWaitMin > o.RetryWaitMax {
+		add("retry_wait_min", "must not be greater than retry_wait_max (%s > %s)", o.RetryWaitMin, o.RetryWaitMax)
+	}
+
+	if o.RetryTimeout < 0 {
+		add("retry_timeout", "must not be negative")
+	}
+
+	if o.MaxResponseBodySize < 0 {
+		add("max_response_body_size", "must not be negative")
+	}
+
+	for _, v := range []struct {
+		field string
+		value int64
+	}{
+		{"transport.max_connections", int64(o.MaxConnections)},
+		{"transport.max_conns_per_host", int64(o.MaxConnsPerHost)},
+		{"transport.dial_timeout", int64(o.DialTimeout)},
+		{"transport.idle_conn_timeout", int64(o.IdleConnTimeout)},
+		{"transport.tls_handshake_timeout", int64(o.TLSHandshakeTimeout)},
+		{"transport.response_header_timeout", int64(o.ResponseHeaderTimeout)},
+		{"transport.expect_continue_timeout", int64(o.ExpectContinueTimeout)},
+	} {
+		if v.value < 0 {
+			add(v.field, "must not be negative")
+		}
+	}
+
+	switch o.RetryBackoff {
+	case "", BackoffExponential, BackoffLinearJitter, BackoffConstant, BackoffDecorrelatedJitter:
+	default:
+		add("retry.backoff", "unsupported backoff %q", o.RetryBackoff)
+	}
+
+	if o.RetryJitter < 0 || o.RetryJitter > 1 {
+		add("retry.jitter", "must be in [0, 1]")
+	}
+
+	retryValue := NewRetryValue(o.OptionRetryFns...)
+	for _, code := range retryValue.EnabledStatusCodes {
+		if code < 100 || code > 599 {
+			add("retry.enabled_status_codes", "invalid status code %d", code)
+		}
+	}
+
+	for _, code := range retryValue.DisabledStatusCodes {
+		if code < 100 || code > 599 {
+			add("retry.disabled_status_codes", "invalid status code %d", code)
+		}
+	}
+
+	if o.Proxy != "" {
+		if u, err := url.Parse(o.Proxy); err != nil {
+			add("proxy", "invalid url: %v", err)
+		} else if !slices.Contains([]string{"http", "https", "socks5", "socks5h"}, u.Scheme) || u.Host == "" {
+			add("proxy", "must be an http, https or socks5 url with host")
+		}
+	}
+
+	if o.TLSConfig != nil {
+		validateTLS(o.TLSConfig, add)
+	}
+
+	if o.CompressionEncoding != "" && o.CompressionEncoding != EncodingGzip && o.CompressionEncoding != EncodingZstd {
+		add("compression.encoding", "unsupported encoding %q, use %s or %s", o.CompressionEncoding, EncodingGzip, EncodingZstd)
+	}
+
+	if o.CompressionMinSize < 0 {
+		add("compression.min_size", "must not be negative")
+	}
+
+	if !o.DisableDecompression {
+		for _, encoding := range o.AcceptEncoding {
+			if !isSupportedEncoding(encoding) {
+				add("compression.accept_encoding", "unsupported encoding %q", encoding)
+			}
+		}
+	}
+
+	switch o.BodyReplay {
+	case BodyReplayDefault, BodyReplayBuffer, BodyReplayDisableRetry:
+	default:
+		add("body_replay", "unsupported mode %q", o.BodyReplay)
+	}
+
+	if o.BodyReplayMemoryLimit < 0 {
+		add("body_replay_memory_limit", "must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateTLS(t *TLSConfig, add func(field, format string, args ...any)) {
+	hasKey := t.Key != "" || t.KeyFile != ""
+	hasCert := t.Cert != "" || t.CertFile != ""
+
+	if t.CertFile != "" && !hasKey {
+		add("tls.key_file", "required when cert_file is set")
+	}
+
+	if t.Cert != "" && !hasKey {
+		add("tls.key", "required when cert is set")
+	}
+
+	if t.KeyFile != "" && !hasCert {
+		add("tls.cert_file", "required when key_file is set")
+	}
+
+	if t.Key != "" && !hasCert {
+		add("tls.cert", "required when key is set")
+	}
+
+	if t.Cert != "" && t.CertFile != "" {
+		add("tls.cert", "can not be used with cert_file")
+	}
+
+	if t.Key != "" && t.KeyFile != "" {
+		add("tls.key", "can not be used with key_file")
+	}
+
+	if t.PKCS12 != "" && t.PKCS12File != "" {
+		add("tls.pkcs12", "can not be used with pkcs12_file")
+	}
+
+	if (t.PKCS12 != "" || t.PKCS12File != "") && (hasCert || hasKey) {
+		add("tls.pkcs12", "can not be used with cert and key")
+	}
+
+	if t.DisableSystemCA && t.CA == "" && t.CAFile == "" {
+		add("tls.disable_system_ca", "requires ca or ca_file")
+	}
+
+	minVersion, minOK := tlsVersions[t.MinVersion]
+	if t.MinVersion != "" && !minOK {
+		add("tls.min_version", "unsupported version %q, use 1.0, 1.1, 1.2 or 1.3", t.MinVersion)
+	}
+
+	maxVersion, maxOK := tlsVersions[t.MaxVersion]
+	if t.MaxVersion != "" && !maxOK {
+		add("tls.max_version", "unsupported version %q, use 1.0, 1.1, 1.2 or 1.3", t.MaxVersion)
+	}
+
+	if minOK && maxOK && minVersion > maxVersion {
+		add("tls.min_version", "must not be greater than max_version")
+	}
+
+	for _, name := range t.CipherSuites {
+		if _, ok := cipherSuite(name); !ok {
+			add("tls.cipher_suites", "unsupported cipher suite %q", name)
+		}
+	}
+
+	for _, pin := range slices.Concat(t.Pins, t.BackupPins) {
+		if _, err := parsePin(pin); err != nil {
+			add("tls.pins", "%v", err)
+		}
+	}
+
+	if len(t.Pins) == 0 && len(t.BackupPins) > 0 {
+		add("tls.backup_pins", "requires pins")
+	}
+
+	if len(t.Pins) == 0 && t.PinsReportOnly {
+		add("tls.pins_report_only", "requires pins")
+	}
+
+	if t.ReloadInterval < 0 {
+		add("tls.reload_interval", "must not be negative")
+	}
+}
@@ -0,0 +1,134 @@
+package klient
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		fields []string
+	}{
+		{
+			name: "valid",
+			config: Config{
+				BaseURL:      "http://example.com",
+				RetryWaitMin: time.Second,
+				RetryWaitMax: 2 * time.Second,
+				Proxy:        "http://proxy:3128",
+				TLSConfig:    &TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"},
+			},
+		},
+		{
+			name: "invalid",
+			config: Config{
+				BaseURL:      "http://exa mple.com",
+				RetryMax:     -1,
+				RetryWaitMin: 2 * time.Second,
+				RetryWaitMax: time.Second,
+				Proxy:        "://proxy",
+				TLSConfig:    &TLSConfig{CertFile: "cert.pem"},
+				Compression:  &CompressionConfig{Encoding: EncodingBrotli, AcceptEncoding: []string{"lz4"}},
+				BodyReplay:   "memory",
+			},
+			fields: []string{
+				"base_url",
+				"retry_max",
+				"retry_wait_min",
+				"proxy",
+				"tls.key_file",
+				"compression.encoding",
+				"compression.accept_encoding",
+				"body_replay",
+			},
+		},
+		{
+			name: "invalid tls",
+			config: Config{
+				TLSConfig: &TLSConfig{
+					Cert:            "pem",
+					PKCS12File:      "client.p12",
+					DisableSystemCA: true,
+					MinVersion:      "1.3",
+					MaxVersion:      "1.2",
+					CipherSuites:    []string{"TLS_NULL"},
+					Pins:            []string{"sha256/abc"},
+				},
+			},
+			fields: []string{
+				"tls.key",
+				"tls.pkcs12",
+				"tls.disable_system_ca",
+				"tls.min_version",
+				"tls.cipher_suites",
+				"tls.pins",
+			},
+		},
+		{
+			name:   "wait min greater than default max",
+			config: Config{RetryWaitMin: time.Hour},
+			fields: []string{"retry_wait_min"},
+		},
+		{
+			name:   "relative base url",
+			config: Config{BaseURL: "example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+
+			var fields []string
+			if err == nil {
+				if len(tt.fields) > 0 {
+					t.Fatalf("expected errors of %v", tt.fields)
+				}
+
+				return
+			}
+
+			for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+				var fieldErr *FieldError
+				if !errors.As(e, &fieldErr) {
+					t.Fatalf("unexpected error type %T", e)
+				}
+
+				fields = append(fields, fieldErr.Field)
+			}
+
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("fields %v, want %v\n%v", fields, tt.fields, err)
+			}
+
+			if len(tt.fields) > 0 && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("expected ErrInvalidConfig, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewValidate(t *testing.T) {
+	_, err := New(WithDisableBaseURLCheck(true), WithRetryWaitMin(time.Minute), WithRetryWaitMax(time.Second))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected invalid config error, got %v", err)
+	}
+
+	_, err = New(WithDisableBaseURLCheck(true), WithRetryWaitMin(time.Minute))
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected invalid config error of wait min over the default max, got %v", err)
+	}
+
+	if _, err := New(WithBaseURL("localhost/api"), WithRetryWaitMin(10*time.Second)); err != nil {
+		t.Fatalf("expected relative base url and only wait min to be accepted, got %v", err)
+	}
+
+	config := Config{BaseURL: "http://example.com", TLSConfig: &TLSConfig{KeyFile: "key.pem"}}
+	if _, err := config.New(); err == nil || err.Error() != "invalid config: tls.cert_file: required when key_file is set" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}